@@ -1,16 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"strings"
 
-	"github.com/spf13/cobra"
-	"github.com/ironicbadger/jankey/internal/apikey"
 	"github.com/ironicbadger/jankey/internal/config"
-	"github.com/ironicbadger/jankey/internal/oauth"
-	"github.com/ironicbadger/jankey/internal/pass"
 	"github.com/ironicbadger/jankey/internal/tailscale"
-	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/spf13/cobra"
 )
 
 var (
@@ -23,7 +20,8 @@ var cleanupCmd = &cobra.Command{
 	Short: "Manage auth keys created by jankey",
 	Long: `List and clean up auth keys that were created by jankey.
 
-Auth keys created by jankey have "Generated by jankey" in their description.
+Auth keys created by jankey have "Generated by jankey" in their description,
+including keys created with a preset description.
 This command helps you manage and remove old or unused keys.`,
 	RunE: runCleanup,
 }
@@ -36,62 +34,27 @@ func init() {
 }
 
 func runCleanup(cmd *cobra.Command, args []string) error {
-	// Get config path
-	configPath := cfgFile
-	if configPath == "" {
-		var err error
-		configPath, err = config.GetConfigPath()
-		if err != nil {
-			return fmt.Errorf("failed to get config path: %w", err)
-		}
-	}
-
 	// Load configuration
-	cfg, err := config.LoadOrDefault(configPath)
+	cfg, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	// Initialize pass client
-	var passClient *pass.Client
-	if pass.IsInstalled() {
-		passClient, err = pass.New()
-		if err != nil && verbose {
-			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-		}
-	}
-
-	// Determine auth method and get credentials
-	if useOAuth {
-		return cleanupWithOAuth(cfg, passClient)
+		return err
 	}
 
-	return cleanupWithAPIKey(cfg, passClient)
-}
-
-func cleanupWithAPIKey(cfg *models.Config, passClient *pass.Client) error {
-	// Get API key
-	apiKeyValue, err := pass.GetFromPassOrEnv(passClient, cfg.APIKey.PassPathAPIKey, "TS_API_KEY")
+	// Create API client for the selected authentication method
+	ctx := cmd.Context()
+	client, err := newAPIClient(ctx, cfg, newPassClient(cfg))
 	if err != nil {
-		return fmt.Errorf("failed to get API key: %w\n\nRun with --init to configure credentials or set TS_API_KEY environment variable", err)
-	}
-
-	// Create API key client
-	apiClient := apikey.New(apiKeyValue, verbose)
-
-	// Validate API key
-	if err := apiClient.ValidateAPIKey(); err != nil {
-		return fmt.Errorf("API key validation failed: %w", err)
+		return err
 	}
 
 	// List auth keys
-	keys, err := apiClient.ListAuthKeys()
+	keys, err := client.ListAuthKeys(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list auth keys: %w", err)
 	}
 
 	// Filter for jankey-created keys
-	jankeyKeys := filterJankeyKeysGeneric(keys)
+	jankeyKeys := filterJankeyKeys(keys)
 
 	if len(jankeyKeys) == 0 {
 		fmt.Println("No auth keys created by jankey found.")
@@ -100,7 +63,7 @@ func cleanupWithAPIKey(cfg *models.Config, passClient *pass.Client) error {
 
 	// Display found keys
 	fmt.Printf("Found %d auth key(s) created by jankey:\n\n", len(jankeyKeys))
-	displayAuthKeysGeneric(jankeyKeys)
+	displayAuthKeys(jankeyKeys)
 
 	// Handle deletion
 	if cleanupAll {
@@ -110,62 +73,7 @@ func cleanupWithAPIKey(cfg *models.Config, passClient *pass.Client) error {
 		}
 
 		fmt.Printf("\nDeleting %d auth key(s)...\n", len(jankeyKeys))
-		return deleteAuthKeys(apiClient, jankeyKeys)
-	}
-
-	fmt.Println("\nUse --all to delete these keys, or --dry-run to preview deletion.")
-	return nil
-}
-
-func cleanupWithOAuth(cfg *models.Config, passClient *pass.Client) error {
-	// Get OAuth credentials
-	clientID, err := pass.GetFromPassOrEnv(passClient, cfg.OAuth.PassPathClientID, "TS_OAUTH_CLIENT_ID")
-	if err != nil {
-		return fmt.Errorf("failed to get OAuth client ID: %w\n\nRun with --init to configure credentials", err)
-	}
-
-	clientSecret, err := pass.GetFromPassOrEnv(passClient, cfg.OAuth.PassPathClientSecret, "TS_OAUTH_CLIENT_SECRET")
-	if err != nil {
-		return fmt.Errorf("failed to get OAuth client secret: %w\n\nRun with --init to configure credentials", err)
-	}
-
-	// Get OAuth access token
-	oauthClient := oauth.New(clientID, clientSecret, verbose)
-	accessToken, err := oauthClient.GetAccessToken()
-	if err != nil {
-		return fmt.Errorf("failed to get OAuth access token: %w", err)
-	}
-
-	// Create Tailscale client
-	tsClient := tailscale.New(accessToken, verbose)
-
-	// List auth keys
-	keys, err := tsClient.ListAuthKeys()
-	if err != nil {
-		return fmt.Errorf("failed to list auth keys: %w", err)
-	}
-
-	// Filter for jankey-created keys
-	jankeyKeys := filterJankeyKeysGeneric(keys)
-
-	if len(jankeyKeys) == 0 {
-		fmt.Println("No auth keys created by jankey found.")
-		return nil
-	}
-
-	// Display found keys
-	fmt.Printf("Found %d auth key(s) created by jankey:\n\n", len(jankeyKeys))
-	displayAuthKeysGeneric(jankeyKeys)
-
-	// Handle deletion
-	if cleanupAll {
-		if cleanupDryRun {
-			fmt.Printf("\n[DRY RUN] Would delete %d auth key(s)\n", len(jankeyKeys))
-			return nil
-		}
-
-		fmt.Printf("\nDeleting %d auth key(s)...\n", len(jankeyKeys))
-		return deleteAuthKeys(tsClient, jankeyKeys)
+		return deleteAuthKeys(ctx, client, jankeyKeys)
 	}
 
 	fmt.Println("\nUse --all to delete these keys, or --dry-run to preview deletion.")
@@ -174,89 +82,52 @@ func cleanupWithOAuth(cfg *models.Config, passClient *pass.Client) error {
 
 func containsJankeySignature(description string) bool {
 	// Check for our signature in the description
-	return contains(description, "Generated by jankey") ||
-	       contains(description, "🤖 Generated with [Claude Code]")
+	return strings.Contains(description, config.DefaultDescription) ||
+		strings.Contains(description, "🤖 Generated with [Claude Code]")
 }
 
-func displayAuthKeysGeneric(keys []AuthKeyInfo) {
-	for i, key := range keys {
-		fmt.Printf("%d. ID: %s\n", i+1, key.GetID())
-		fmt.Printf("   Created: %s\n", key.GetCreated())
-		fmt.Printf("   Expires: %s\n", key.GetExpires())
+func filterJankeyKeys(keys []tailscale.AuthKey) []tailscale.AuthKey {
+	var jankeyKeys []tailscale.AuthKey
 
-		if key.GetDescription() != "" {
-			fmt.Printf("   Description: %s\n", key.GetDescription())
+	for _, key := range keys {
+		if containsJankeySignature(key.Description) {
+			jankeyKeys = append(jankeyKeys, key)
 		}
-
-		fmt.Println()
 	}
-}
 
-type AuthKeyInfo interface {
-	GetID() string
-	GetCreated() string
-	GetExpires() string
-	GetDescription() string
-}
-
-type AuthKeyDeleter interface {
-	DeleteAuthKey(keyID string) error
+	return jankeyKeys
 }
 
-func filterJankeyKeysGeneric(keys interface{}) []AuthKeyInfo {
-	var jankeyKeys []AuthKeyInfo
+func displayAuthKeys(keys []tailscale.AuthKey) {
+	for i, key := range keys {
+		fmt.Printf("%d. ID: %s\n", i+1, key.ID)
+		fmt.Printf("   Created: %s\n", key.Created.Format("2006-01-02 15:04:05"))
+		fmt.Printf("   Expires: %s\n", key.Expires.Format("2006-01-02 15:04:05"))
 
-	// Handle both apikey.AuthKey and tailscale.AuthKey
-	switch k := keys.(type) {
-	case []apikey.AuthKey:
-		for _, key := range k {
-			if containsJankeySignature(key.Description) {
-				jankeyKeys = append(jankeyKeys, authKeyWrapper{
-					id:          key.ID,
-					created:     key.Created.Format("2006-01-02 15:04:05"),
-					expires:     key.Expires.Format("2006-01-02 15:04:05"),
-					description: key.Description,
-				})
-			}
-		}
-	case []tailscale.AuthKey:
-		for _, key := range k {
-			if containsJankeySignature(key.Description) {
-				jankeyKeys = append(jankeyKeys, authKeyWrapper{
-					id:          key.ID,
-					created:     key.Created.Format("2006-01-02 15:04:05"),
-					expires:     key.Expires.Format("2006-01-02 15:04:05"),
-					description: key.Description,
-				})
-			}
+		if key.Description != "" {
+			fmt.Printf("   Description: %s\n", key.Description)
 		}
-	}
-
-	return jankeyKeys
-}
 
-type authKeyWrapper struct {
-	id          string
-	created     string
-	expires     string
-	description string
+		fmt.Println()
+	}
 }
 
-func (a authKeyWrapper) GetID() string          { return a.id }
-func (a authKeyWrapper) GetCreated() string     { return a.created }
-func (a authKeyWrapper) GetExpires() string     { return a.expires }
-func (a authKeyWrapper) GetDescription() string { return a.description }
-
-func deleteAuthKeys(client AuthKeyDeleter, keys []AuthKeyInfo) error {
+func deleteAuthKeys(ctx context.Context, client *tailscale.Client, keys []tailscale.AuthKey) error {
 	deletedCount := 0
 	errorCount := 0
 
 	for _, key := range keys {
-		if err := client.DeleteAuthKey(key.GetID()); err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Failed to delete key %s: %v\n", key.GetID(), err)
+		// Stop deleting once interrupted or timed out
+		if ctx.Err() != nil {
+			fmt.Printf("\nDeleted %d key(s) before stopping\n", deletedCount)
+			return ctx.Err()
+		}
+
+		if err := client.DeleteAuthKey(ctx, key.ID); err != nil {
+			logger.Error("failed to delete auth key", "id", key.ID, "error", err)
 			errorCount++
 		} else {
-			fmt.Printf("✓ Deleted key %s\n", key.GetID())
+			fmt.Printf("✓ Deleted key %s\n", key.ID)
 			deletedCount++
 		}
 	}
@@ -273,8 +144,3 @@ func deleteAuthKeys(client AuthKeyDeleter, keys []AuthKeyInfo) error {
 
 	return nil
 }
-
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
-		(len(s) > 0 && (s[:len(substr)] == substr || contains(s[1:], substr))))
-}
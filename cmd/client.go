@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ironicbadger/jankey/internal/config"
+	"github.com/ironicbadger/jankey/internal/credential"
+	"github.com/ironicbadger/jankey/internal/keyring"
+	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/ironicbadger/jankey/internal/oauth"
+	"github.com/ironicbadger/jankey/internal/pass"
+	"github.com/ironicbadger/jankey/internal/retry"
+	"github.com/ironicbadger/jankey/internal/tailscale"
+)
+
+// resolveConfigPath returns --config or the default config path
+func resolveConfigPath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config path: %w", err)
+	}
+	return configPath, nil
+}
+
+// loadConfig loads the configuration from --config or the default path and
+// applies the selected profile
+func loadConfig() (*models.Config, error) {
+	cfg, err := loadBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	name := config.SelectedProfile(cfg, profileName)
+	if name == "" {
+		return cfg, nil
+	}
+
+	cfg, err = config.ApplyProfile(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("using profile", "profile", name)
+	return cfg, nil
+}
+
+// loadBaseConfig loads the configuration without applying a profile
+func loadBaseConfig() (*models.Config, error) {
+	layered, err := loadLayers()
+	if err != nil {
+		return nil, err
+	}
+	return layered.Config, nil
+}
+
+// loadLayers merges the config layers, with --config as the user layer
+func loadLayers() (*config.Layered, error) {
+	configPath, err := resolveConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	layered, err := config.LoadLayers(config.LoadOptions{UserPath: configPath})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	for _, file := range layered.Files {
+		logger.Debug("loaded config file", "layer", file.Layer, "path", file.Location)
+	}
+
+	return layered, nil
+}
+
+// oauthSelected reports whether OAuth authentication is selected by
+// --use-oauth or the config's auth_method
+func oauthSelected(cfg *models.Config) bool {
+	return useOAuth || cfg.AuthMethod == models.AuthMethodOAuth
+}
+
+// newPassClient returns a client for the configured password store, or nil
+// if it is not available
+func newPassClient(cfg *models.Config) *pass.Client {
+	passClient, err := pass.New(cfg.Pass.Backend)
+	if err != nil {
+		logger.Debug("pass is unavailable", "error", err)
+		return nil
+	}
+
+	return passClient
+}
+
+// keyringUsed reports whether the config names keyring items, so the
+// session bus is only contacted when the keyring is used
+func keyringUsed(cfg *models.Config) bool {
+	return cfg.APIKey.KeyringAPIKey != "" || cfg.OAuth.KeyringClientID != "" || cfg.OAuth.KeyringClientSecret != ""
+}
+
+// newKeyringClient returns a Secret Service client, or nil if the config
+// names no keyring items or no keyring is available. The caller closes it.
+func newKeyringClient(cfg *models.Config) *keyring.Client {
+	if !keyringUsed(cfg) {
+		return nil
+	}
+
+	keyringClient, err := keyring.New()
+	if err != nil {
+		logger.Debug("keyring is unavailable", "error", err)
+		return nil
+	}
+
+	return keyringClient
+}
+
+// newAPIClient resolves credentials for the selected authentication method
+// and returns a Tailscale API client using them
+func newAPIClient(ctx context.Context, cfg *models.Config, passClient *pass.Client) (*tailscale.Client, error) {
+	opts, err := resolveAPIOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := newAuthenticator(ctx, cfg, passClient, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return tailscale.New(auth, opts), nil
+}
+
+// resolveAPIOptions determines the API base URL and tailnet from flags,
+// environment variables and config, in that order of precedence
+func resolveAPIOptions(cfg *models.Config) (tailscale.Options, error) {
+	opts := tailscale.Options{
+		BaseURL: firstNonEmpty(apiURL, os.Getenv("TS_API_URL"), cfg.API.BaseURL),
+		Tailnet: firstNonEmpty(tailnet, os.Getenv("TS_TAILNET"), cfg.API.Tailnet),
+		Retry: retry.Policy{
+			MaxRetries: maxRetries,
+			BaseDelay:  retry.DefaultBaseDelay,
+			MaxDelay:   retry.DefaultMaxDelay,
+			Budget:     retryBudget,
+		},
+		Logger: logger,
+	}
+
+	if maxRetries < 0 {
+		return opts, fmt.Errorf("--max-retries must not be negative")
+	}
+
+	if opts.BaseURL != "" {
+		if err := tailscale.ValidateBaseURL(opts.BaseURL); err != nil {
+			return opts, err
+		}
+	}
+
+	return opts, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func newAuthenticator(ctx context.Context, cfg *models.Config, passClient *pass.Client, opts tailscale.Options) (tailscale.Authenticator, error) {
+	// The credentials are read before returning, so the keyring can be closed
+	keyringClient := newKeyringClient(cfg)
+	if keyringClient != nil {
+		defer keyringClient.Close()
+	}
+	credOpts := credential.Options{Pass: passClient, Keyring: keyringClient, Logger: logger}
+
+	if oauthSelected(cfg) {
+		// Get OAuth credentials
+		idChain, secretChain, err := credential.OAuthClient(cfg, credOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		clientID, _, err := idChain.Get(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w\n\nRun with --init to configure credentials", err)
+		}
+
+		clientSecret, _, err := secretChain.Get(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w\n\nRun with --init to configure credentials", err)
+		}
+
+		// Exchange the client credentials for an access token
+		oauthClient := oauth.New(clientID, clientSecret, oauth.Options{
+			BaseURL: opts.BaseURL,
+			Retry:   opts.Retry,
+			Logger:  logger,
+		})
+		accessToken, err := oauthClient.GetAccessToken(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get OAuth access token: %w", err)
+		}
+
+		return tailscale.BearerToken(accessToken), nil
+	}
+
+	// Default: API key authentication
+	chain, err := credential.APIKey(cfg, credOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeyValue, _, err := chain.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w\n\nRun with --init to configure credentials or set TS_API_KEY environment variable", err)
+	}
+
+	if err := tailscale.ValidateAPIKey(apiKeyValue); err != nil {
+		return nil, fmt.Errorf("API key validation failed: %w", err)
+	}
+
+	logger.Debug("API key format check passed",
+		"note", "API keys expire 90 days after creation; regenerate at https://login.tailscale.com/admin/settings/keys")
+
+	return tailscale.APIKey(apiKeyValue), nil
+}
@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/ironicbadger/jankey/internal/config"
+	"github.com/ironicbadger/jankey/internal/output"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and manage the configuration",
+	Long: `Inspect and manage the configuration.
+
+The configuration is merged from these layers, later ones taking precedence:
+  default   built-in defaults
+  system    ` + config.SystemConfigPath + `
+  user      ~/.config/jankey/config.yaml (or --config)
+  project   ` + config.ProjectConfigFile + ` in the current directory or a parent
+  env       JANKEY_* environment variables, e.g. JANKEY_AUTH_KEY_DEFAULTS_TAGS=tag:ci,tag:web
+
+A project config may not set api.base_url, api_key, oauth, output, pass or
+profiles.
+
+Keys are dotted paths such as auth_key_defaults.expiry_days or
+presets.ci.tags. set, unset and edit change the user config file, keeping its
+comments and key order, and refuse to save an invalid configuration.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print the effective value of a config key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Set a config key in the user config file (lists are comma-separated)",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigSet,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset KEY",
+	Short: "Remove a config key from the user config file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigUnset,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [FILE]",
+	Short: "Validate the effective configuration, or a single config file",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runConfigValidate,
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the user config file in $VISUAL or $EDITOR",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigEdit,
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the user config file",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigPath,
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the user config file to the current format version",
+	Long: `Upgrade the user config file to the current format version.
+
+Older config files are upgraded in memory whenever they are read. migrate
+prints the upgraded file; with --write it replaces the file, first copying the
+original to <file>.v<version>.bak.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigMigrate,
+}
+
+var configMigrateWrite bool
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print a JSON Schema for the config file",
+	Long: `Print a JSON Schema for the config file, for completion and validation in
+editors. For example, with the YAML language server:
+
+  jankey config schema > ~/.config/jankey/config.schema.json
+
+and as the first line of config.yaml:
+
+  # yaml-language-server: $schema=./config.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: runConfigSchema,
+}
+
+var configExplainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Show each effective config value and the layer it came from",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigExplain,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configExplainCmd, configGetCmd, configSetCmd, configUnsetCmd, configValidateCmd, configEditCmd, configPathCmd, configMigrateCmd, configSchemaCmd)
+
+	configMigrateCmd.Flags().BoolVar(&configMigrateWrite, "write", false, "Replace the config file with the upgraded version")
+}
+
+func runConfigExplain(cmd *cobra.Command, args []string) error {
+	layered, err := loadLayers()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Config files (lowest precedence first):")
+	if len(layered.Files) == 0 {
+		fmt.Println("  none, using built-in defaults")
+	}
+	for _, file := range layered.Files {
+		fmt.Printf("  %-8s %s\n", file.Layer, file.Location)
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, v := range layered.Explain() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, v.Source)
+	}
+	return w.Flush()
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	key := args[0]
+	if _, err := config.KeyType(key); err != nil {
+		return err
+	}
+
+	layered, err := loadLayers()
+	if err != nil {
+		return err
+	}
+
+	value, ok := layered.Get(key)
+	if !ok {
+		return fmt.Errorf("%s is not set", key)
+	}
+
+	switch value.(type) {
+	case map[string]any, []any:
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(value); err != nil {
+			return fmt.Errorf("failed to marshal value: %w", err)
+		}
+		return enc.Close()
+	default:
+		fmt.Println(value)
+	}
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	doc, err := openUserConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := doc.Set(args[0], args[1]); err != nil {
+		return err
+	}
+
+	return doc.Save()
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	doc, err := openUserConfig()
+	if err != nil {
+		return err
+	}
+
+	found, err := doc.Unset(args[0])
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%s is not set in %s", args[0], doc.Path())
+	}
+
+	return doc.Save()
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	if len(args) == 1 {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		doc, err := config.ParseDocument(args[0], data)
+		if err != nil {
+			return err
+		}
+		if err := doc.Validate(); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s is valid.\n", args[0])
+		return nil
+	}
+
+	if _, err := loadLayers(); err != nil {
+		return err
+	}
+
+	fmt.Println("Configuration is valid.")
+	return nil
+}
+
+func runConfigEdit(cmd *cobra.Command, args []string) error {
+	configPath, err := resolveConfigPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Edit a private copy so an invalid result never replaces the config
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(configPath), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		if err := runEditor(tmp.Name()); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read edited config: %w", err)
+		}
+
+		doc, err := config.ParseDocument(configPath, edited)
+		if err == nil {
+			err = doc.Validate()
+		}
+		if err == nil {
+			if err := os.WriteFile(configPath, edited, 0600); err != nil {
+				return fmt.Errorf("failed to write config file: %w", err)
+			}
+			fmt.Printf("Saved %s.\n", configPath)
+			return nil
+		}
+
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if !promptYesNo(reader, "Edit again?", true) {
+			return fmt.Errorf("changes discarded: %w", err)
+		}
+	}
+}
+
+// runEditor opens path in $VISUAL, $EDITOR or vi
+func runEditor(path string) error {
+	editor := firstNonEmpty(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi")
+
+	// The editor may include arguments, e.g. "code --wait"
+	c := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+	return nil
+}
+
+func runConfigPath(cmd *cobra.Command, args []string) error {
+	configPath, err := resolveConfigPath()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(configPath)
+	return nil
+}
+
+func runConfigMigrate(cmd *cobra.Command, args []string) error {
+	configPath, err := resolveConfigPath()
+	if err != nil {
+		return err
+	}
+
+	original, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	doc, err := config.ParseDocument(configPath, original)
+	if err != nil {
+		return err
+	}
+
+	migrations := doc.Migrations()
+	if len(migrations) == 0 {
+		fmt.Printf("%s is up to date (version %d).\n", configPath, config.CurrentVersion)
+		return nil
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if !configMigrateWrite {
+		os.Stdout.Write(data)
+		return nil
+	}
+
+	if err := doc.Validate(); err != nil {
+		return err
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", configPath, migrations[0].From)
+	if err := output.WriteFile(backup, original, output.DefaultFileOptions()); err != nil {
+		return fmt.Errorf("failed to back up config file: %w", err)
+	}
+	if err := output.WriteFile(configPath, data, output.DefaultFileOptions()); err != nil {
+		return err
+	}
+
+	fmt.Printf("Migrated %s to version %d:\n", configPath, config.CurrentVersion)
+	for _, m := range migrations {
+		fmt.Printf("  v%d -> v%d: %s\n", m.From, m.From+1, m.Description)
+	}
+	fmt.Printf("The original was saved to %s.\n", backup)
+	return nil
+}
+
+func runConfigSchema(cmd *cobra.Command, args []string) error {
+	data, err := json.MarshalIndent(config.Schema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	fmt.Println(string(data))
+	return nil
+}
+
+// openUserConfig opens the user config file for editing
+func openUserConfig() (*config.Document, error) {
+	configPath, err := resolveConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return config.OpenDocument(configPath)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/fakeapi"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fakeAPIListen       string
+	fakeAPIKeys         []string
+	fakeAPIOAuthClients []string
+	fakeAPITailnet      string
+	fakeAPIAllowedTags  string
+	fakeAPIOAuthScope   string
+	fakeAPIFaults       []string
+)
+
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Developer and testing tools",
+}
+
+var fakeAPICmd = &cobra.Command{
+	Use:   "fake-api",
+	Short: "Run an in-memory fake Tailscale API server",
+	Long: `Run an in-memory stand-in for the Tailscale API endpoints used by jankey,
+for offline and end-to-end testing.
+
+Point jankey at it with --api-url or TS_API_URL. Faults can be injected at
+startup with --fault, or at runtime by POSTing a JSON array of fault specs to
+/_fake/faults (GET lists queued faults, DELETE clears them).
+
+Fault specs are comma-separated options:
+  status=CODE        respond with an HTTP status code
+  retry-after=DUR    send a Retry-After header
+  delay=DUR          wait before responding
+  reset              reset the connection
+  count=N            apply to N requests (default 1)
+  method=METHOD      only match requests with this method
+  path=SUBSTRING     only match request paths containing SUBSTRING
+
+Example:
+  jankey dev fake-api --fault status=429,retry-after=2 --fault status=503,path=/keys`,
+	RunE: runFakeAPI,
+}
+
+func init() {
+	rootCmd.AddCommand(devCmd)
+	devCmd.AddCommand(fakeAPICmd)
+
+	fakeAPICmd.Flags().StringVar(&fakeAPIListen, "listen", "127.0.0.1:8080", "address to listen on")
+	fakeAPICmd.Flags().StringArrayVar(&fakeAPIKeys, "api-key", []string{"tskey-api-fake"}, "accepted API key (repeatable)")
+	fakeAPICmd.Flags().StringArrayVar(&fakeAPIOAuthClients, "oauth-client", []string{"fake-client-id:fake-client-secret"}, "accepted OAuth client as ID:SECRET (repeatable)")
+	fakeAPICmd.Flags().StringVar(&fakeAPITailnet, "tailnet", "example.com", "tailnet name accepted in addition to '-'")
+	fakeAPICmd.Flags().StringVar(&fakeAPIAllowedTags, "allowed-tags", "", "comma-separated list of tags auth keys may use, and the policy's tagOwners (default: any)")
+	fakeAPICmd.Flags().StringVar(&fakeAPIOAuthScope, "oauth-scope", fakeapi.DefaultOAuthScope, "space-separated scopes granted to OAuth clients")
+	fakeAPICmd.Flags().StringArrayVar(&fakeAPIFaults, "fault", nil, "fault to inject, applied in order (repeatable)")
+}
+
+func runFakeAPI(cmd *cobra.Command, args []string) error {
+	cfg := fakeapi.Config{
+		APIKeys:      fakeAPIKeys,
+		OAuthClients: make(map[string]string),
+		Tailnet:      fakeAPITailnet,
+		OAuthScope:   fakeAPIOAuthScope,
+	}
+
+	for _, client := range fakeAPIOAuthClients {
+		id, secret, ok := strings.Cut(client, ":")
+		if !ok || id == "" || secret == "" {
+			return fmt.Errorf("invalid --oauth-client '%s': expected ID:SECRET", client)
+		}
+		cfg.OAuthClients[id] = secret
+	}
+
+	if fakeAPIAllowedTags != "" {
+		cfg.AllowedTags = parseTags(fakeAPIAllowedTags)
+	}
+
+	server := fakeapi.New(cfg)
+
+	for _, spec := range fakeAPIFaults {
+		fault, err := fakeapi.ParseFault(spec)
+		if err != nil {
+			return err
+		}
+		server.AddFault(fault)
+	}
+
+	listener, err := net.Listen("tcp", fakeAPIListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", fakeAPIListen, err)
+	}
+
+	logger.Info("fake Tailscale API listening", "url", "http://"+listener.Addr().String())
+
+	httpServer := &http.Server{Handler: server}
+	go func() {
+		<-cmd.Context().Done()
+		httpServer.Close()
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,474 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ironicbadger/jankey/internal/config"
+	"github.com/ironicbadger/jankey/internal/credential"
+	"github.com/ironicbadger/jankey/internal/doctor"
+	"github.com/ironicbadger/jankey/internal/keyring"
+	"github.com/ironicbadger/jankey/internal/logging"
+	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/ironicbadger/jankey/internal/oauth"
+	"github.com/ironicbadger/jankey/internal/output"
+	"github.com/ironicbadger/jankey/internal/pass"
+	"github.com/ironicbadger/jankey/internal/tailscale"
+	"github.com/ironicbadger/jankey/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose configuration, credential and API problems",
+	Long: `Run the checks to go through when jankey fails: whether the config
+validates, pass is installed with an initialized store and a responding GPG
+agent, where the credentials come from (pass or the environment), whether the
+API key format is right, the API can be reached and accepts the credentials,
+the OAuth client has the scopes to create auth keys, and the default tags are
+defined in the tailnet policy file.
+
+Each check passes, warns or fails. The exit status is 1 if any check fails.
+
+--bundle writes a gzipped tar archive with the report, the config files,
+relevant environment variables, system information and a debug log of the
+checks, with secrets masked, to attach to a bug report.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+
+	// Failed checks are an error, but not a usage error
+	SilenceUsage: true,
+}
+
+var (
+	doctorJSON   bool
+	doctorBundle string
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "output the report as JSON")
+	doctorCmd.Flags().StringVar(&doctorBundle, "bundle", "", "write a debug bundle (.tar.gz) with secrets masked to FILE")
+}
+
+// doctorEnvPrefixes select the environment variables in the debug bundle
+var doctorEnvPrefixes = []string{"TS_", "JANKEY_", "PASSWORD_STORE_", "PASSAGE_", "GOPASS_", "GNUPGHOME", "GPG_", "DBUS_SESSION_BUS_ADDRESS"}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	// Record a debug log of the checks, such as API requests, for the bundle
+	var debugLog bytes.Buffer
+	if doctorBundle != "" {
+		bundleLogger, err := logging.New(&debugLog, slog.LevelDebug, logging.FormatJSON)
+		if err != nil {
+			return err
+		}
+		logger = slog.New(slog.NewMultiHandler(logger.Handler(), bundleLogger.Handler()))
+	}
+
+	report := &doctor.Report{}
+
+	layered, cfg := doctorConfig(report)
+	passClient := doctorPass(ctx, report, cfg)
+	keyringClient := doctorKeyring(report, cfg)
+	if keyringClient != nil {
+		defer keyringClient.Close()
+	}
+	creds, ok := doctorCredentials(ctx, report, cfg, credential.Options{Pass: passClient, Keyring: keyringClient, Logger: logger})
+	doctorAPI(ctx, report, cfg, creds, ok)
+
+	var err error
+	if doctorJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	if doctorBundle != "" {
+		if err := writeDoctorBundle(doctorBundle, report, layered, debugLog.Bytes()); err != nil {
+			return err
+		}
+		logger.Info("debug bundle written", "path", doctorBundle)
+	}
+
+	if report.Failed() {
+		return fmt.Errorf("%d doctor checks failed", report.Count(doctor.StatusFail))
+	}
+	return nil
+}
+
+// doctorConfig checks that the config layers load and validate, and that
+// the selected profile applies. On failure the defaults are used for the
+// remaining checks.
+func doctorConfig(report *doctor.Report) (*config.Layered, *models.Config) {
+	layered, err := loadLayers()
+	if err != nil {
+		report.Add("config", doctor.StatusFail, err.Error(), "fix the config file, e.g. with 'jankey config edit'; the defaults are used for the remaining checks")
+		return nil, config.GetDefaultConfig()
+	}
+
+	if len(layered.Files) == 0 {
+		report.Add("config", doctor.StatusWarn, "no config file found, using the built-in defaults", "run 'jankey init' to create one")
+	} else {
+		var files []string
+		for _, file := range layered.Files {
+			files = append(files, file.String())
+		}
+		report.Pass("config", "valid: %s", strings.Join(files, ", "))
+	}
+
+	cfg := layered.Config
+	name := config.SelectedProfile(cfg, profileName)
+	if name == "" {
+		return layered, cfg
+	}
+
+	profileCfg, err := config.ApplyProfile(cfg, name)
+	if err != nil {
+		report.Add("profile", doctor.StatusFail, err.Error(), "run 'jankey profile list' to see the configured profiles")
+		return layered, cfg
+	}
+	report.Pass("profile", "using profile %s", name)
+	return layered, profileCfg
+}
+
+// doctorPass checks that the configured password store tool is installed,
+// its store is initialized and, for GPG-based tools, that the GPG agent it
+// decrypts secrets with responds. It returns nil if the tool is not
+// installed.
+func doctorPass(ctx context.Context, report *doctor.Report, cfg *models.Config) *pass.Client {
+	passClient, err := pass.New(cfg.Pass.Backend)
+	if err != nil {
+		report.Add("pass", doctor.StatusWarn, err.Error(), "credentials must then be set in environment variables")
+		report.Skip("password store", "pass is not installed")
+		report.Skip("gpg agent", "pass is not installed")
+		return nil
+	}
+	report.Pass("pass", "using %s", passClient.Name())
+
+	if dir, err := passClient.CheckStore(); err != nil {
+		report.Add("password store", doctor.StatusWarn, err.Error(), "")
+	} else {
+		report.Pass("password store", "initialized at %s", dir)
+	}
+
+	if !passClient.UsesGPG() {
+		report.Skip("gpg agent", "%s does not use GPG", passClient.Name())
+		return passClient
+	}
+
+	agentCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := pass.CheckGPGAgent(agentCtx); err != nil {
+		report.Add("gpg agent", doctor.StatusWarn, err.Error(), passClient.Name()+" cannot decrypt secrets without it: run 'gpgconf --launch gpg-agent'")
+	} else {
+		report.Pass("gpg agent", "responding")
+	}
+
+	return passClient
+}
+
+// doctorKeyring checks that a Secret Service keyring is available if the
+// config names keyring items. It returns nil otherwise.
+func doctorKeyring(report *doctor.Report, cfg *models.Config) *keyring.Client {
+	if !keyringUsed(cfg) && cfg.Output.StoreKeyring == "" {
+		report.Skip("keyring", "no keyring items configured")
+		return nil
+	}
+
+	keyringClient, err := keyring.New()
+	if err != nil {
+		report.Add("keyring", doctor.StatusWarn, err.Error(), "start GNOME Keyring or KWallet, or move the credentials to another source")
+		return nil
+	}
+	report.Pass("keyring", "Secret Service available")
+	return keyringClient
+}
+
+// doctorCredentials finds the credentials of the selected authentication
+// method, as key generation does, and checks the API key format. It
+// reports whether all credentials were found.
+func doctorCredentials(ctx context.Context, report *doctor.Report, cfg *models.Config, credOpts credential.Options) (verify.Options, bool) {
+	var creds verify.Options
+
+	if oauthSelected(cfg) {
+		idChain, secretChain, err := credential.OAuthClient(cfg, credOpts)
+		if err != nil {
+			report.Add("oauth client", doctor.StatusFail, err.Error(), "fix oauth.sources in the config")
+			return creds, false
+		}
+		creds.ClientID = doctorCredential(ctx, report, "oauth client id", idChain)
+		creds.ClientSecret = doctorCredential(ctx, report, "oauth client secret", secretChain)
+		return creds, creds.ClientID != "" && creds.ClientSecret != ""
+	}
+
+	chain, err := credential.APIKey(cfg, credOpts)
+	if err != nil {
+		report.Add("api key", doctor.StatusFail, err.Error(), "fix api_key.sources in the config")
+		return creds, false
+	}
+
+	creds.APIKey = doctorCredential(ctx, report, "api key", chain)
+	if creds.APIKey == "" {
+		report.Skip("api key format", "no API key found")
+		return creds, false
+	}
+
+	if err := tailscale.ValidateAPIKey(creds.APIKey); err != nil {
+		report.Add("api key format", doctor.StatusFail, err.Error(), "API keys start with tskey-api-: generate one at https://login.tailscale.com/admin/settings/keys")
+		return creds, false
+	}
+	report.Pass("api key format", "looks like a Tailscale API key")
+	return creds, true
+}
+
+// doctorCredential looks up a credential in every source of its chain and
+// reports which one supplies it, failed sources, and sources that also
+// have it but are shadowed
+func doctorCredential(ctx context.Context, report *doctor.Report, name string, chain *credential.Chain) string {
+	var value string
+	var from credential.Provider
+	var failures, shadowed []string
+
+	for _, p := range chain.Providers {
+		v, err := p.Get(ctx)
+		switch {
+		case errors.Is(err, credential.ErrNotFound):
+		case err != nil:
+			failures = append(failures, (&credential.BackendError{Provider: p, Err: err}).Error())
+		case from == nil:
+			value, from = v, p
+		default:
+			shadowed = append(shadowed, p.String())
+		}
+	}
+
+	sources := make([]string, len(chain.Providers))
+	for i, p := range chain.Providers {
+		sources[i] = p.Source()
+	}
+	order := "sources: " + strings.Join(sources, ", ")
+
+	// Other users may be able to read the credential, such as from a file
+	var insecure error
+	if checker, ok := from.(credential.Checker); ok {
+		insecure = checker.Check()
+	}
+
+	switch {
+	case insecure != nil:
+		report.Add(name, doctor.StatusWarn, fmt.Sprintf("using %s, but %v", from, insecure), "")
+	case from == nil && len(failures) > 0:
+		report.Add(name, doctor.StatusFail, strings.Join(failures, "; "), "check that the GPG agent is running and your key is unlocked")
+	case from == nil:
+		report.Add(name, doctor.StatusFail, fmt.Sprintf("not found (%s)", order), "run 'jankey init', or set it in one of the sources")
+	case len(failures) > 0:
+		report.Add(name, doctor.StatusWarn, fmt.Sprintf("using %s, but %s", from, strings.Join(failures, "; ")), "check that the GPG agent is running and your key is unlocked")
+	case len(shadowed) > 0:
+		report.Add(name, doctor.StatusWarn, fmt.Sprintf("using %s; also found in %s, which is ignored", from, strings.Join(shadowed, ", ")),
+			"remove the unused copy so it is clear which is used, or change the order of the sources")
+	default:
+		report.Pass(name, "found in %s (%s)", from, order)
+	}
+
+	return value
+}
+
+// doctorAPI checks that the API can be reached and accepts the
+// credentials, the OAuth scopes, the tailnet and the default tags
+func doctorAPI(ctx context.Context, report *doctor.Report, cfg *models.Config, creds verify.Options, ok bool) {
+	useOAuth := oauthSelected(cfg)
+	skipRest := func(checks []string, format string, args ...any) {
+		for _, name := range checks {
+			if name == "oauth scopes" && !useOAuth {
+				continue
+			}
+			report.Skip(name, format, args...)
+		}
+	}
+	checks := []string{"api", "authentication", "oauth scopes", "tailnet", "tags"}
+
+	if !ok {
+		skipRest(checks, "no usable credentials")
+		return
+	}
+
+	opts, err := resolveAPIOptions(cfg)
+	if err != nil {
+		report.Add("api", doctor.StatusFail, err.Error(), "")
+		skipRest(checks[1:], "the API settings are invalid")
+		return
+	}
+	creds.API = opts
+	creds.Tags = cfg.AuthKeyDefaults.Tags
+
+	result := verify.Verify(ctx, creds)
+	problem := func(kinds ...string) string {
+		for _, p := range result.Problems {
+			for _, kind := range kinds {
+				if p.Kind == kind {
+					return p.Message
+				}
+			}
+		}
+		return ""
+	}
+
+	baseURL := tailscale.NormalizeBaseURL(opts.BaseURL)
+	if !result.Authenticated && result.Has(verify.ProblemAPI) {
+		report.Add("api", doctor.StatusFail, problem(verify.ProblemAPI), "check network access to "+baseURL+", proxy settings and --api-url or TS_API_URL")
+		skipRest(checks[1:], "the API cannot be reached")
+		return
+	}
+	report.Pass("api", "reached %s", baseURL)
+
+	if !result.Authenticated {
+		hint := "API keys expire after 90 days: generate a new one at https://login.tailscale.com/admin/settings/keys"
+		if useOAuth {
+			hint = "check the client ID and secret at https://login.tailscale.com/admin/settings/oauth"
+		}
+		report.Add("authentication", doctor.StatusFail, problem(verify.ProblemCredential, verify.ProblemScope), hint)
+		skipRest(checks[2:], "authentication failed")
+		return
+	}
+	report.Pass("authentication", "%s accepted", result.Credential)
+
+	if useOAuth {
+		switch {
+		case result.Has(verify.ProblemScope):
+			report.Add("oauth scopes", doctor.StatusFail, problem(verify.ProblemScope),
+				"create an OAuth client with one of the scopes "+strings.Join(oauth.AuthKeyScopes, ", ")+" at https://login.tailscale.com/admin/settings/oauth")
+		case len(result.Scopes) == 0:
+			report.Add("oauth scopes", doctor.StatusWarn, "the token response did not list the granted scopes", "")
+		default:
+			report.Pass("oauth scopes", "%s", strings.Join(result.Scopes, ", "))
+		}
+	}
+
+	tailnetName := result.Tailnet
+	if tailnetName == tailscale.DefaultTailnet {
+		tailnetName = "the tailnet of the " + result.Credential
+	}
+	if !result.Listed {
+		hint := ""
+		if result.Has(verify.ProblemTailnet) {
+			hint = "set api.tailnet, --tailnet or TS_TAILNET to your tailnet name, or '-' for the tailnet of the credential"
+		}
+		report.Add("tailnet", doctor.StatusFail, problem(verify.ProblemTailnet, verify.ProblemScope, verify.ProblemAPI), hint)
+		report.Skip("tags", "the tailnet could not be accessed")
+		return
+	}
+	report.Pass("tailnet", "%s: %d auth keys", tailnetName, result.AuthKeys)
+
+	switch {
+	case len(creds.Tags) == 0:
+		report.Skip("tags", "no default tags configured")
+	case result.Has(verify.ProblemPolicy):
+		report.Add("tags", doctor.StatusWarn, problem(verify.ProblemPolicy), "")
+	case result.Has(verify.ProblemTag):
+		report.Add("tags", doctor.StatusFail, strings.Join(result.Tags(), ", ")+" not defined in tagOwners",
+			"add them to the tagOwners section of the policy file at https://login.tailscale.com/admin/acls")
+	default:
+		var owned []string
+		for _, tag := range creds.Tags {
+			owned = append(owned, fmt.Sprintf("%s (%s)", tag, strings.Join(result.TagOwners[tag], ", ")))
+		}
+		report.Pass("tags", "defined in tagOwners: %s", strings.Join(owned, ", "))
+	}
+}
+
+// writeDoctorBundle writes the report, config files, environment, system
+// information and debug log as a gzipped tar archive
+func writeDoctorBundle(path string, report *doctor.Report, layered *config.Layered, debugLog []byte) error {
+	var bundle doctor.Bundle
+
+	var text, jsonReport bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		return err
+	}
+	if err := report.WriteJSON(&jsonReport); err != nil {
+		return err
+	}
+	bundle.Add("report.txt", text.Bytes())
+	bundle.Add("report.json", jsonReport.Bytes())
+	bundle.Add("system.txt", doctorSystemInfo())
+	bundle.Add("environment.txt", doctor.Environ(os.Environ(), doctorEnvPrefixes...))
+
+	if layered != nil {
+		for _, file := range layered.Files {
+			data, err := os.ReadFile(file.Location)
+			if err != nil {
+				continue
+			}
+			bundle.Add("config/"+file.Layer+".yaml", data)
+		}
+
+		var explain bytes.Buffer
+		w := tabwriter.NewWriter(&explain, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		for _, v := range layered.Explain() {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, v.Source)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		bundle.Add("config/effective.txt", explain.Bytes())
+	}
+
+	bundle.Add("doctor.log", debugLog)
+
+	now := time.Now()
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".tar")
+	if name == "" || name == "." {
+		name = "jankey-doctor-" + now.Format("20060102-150405")
+	}
+
+	data, err := bundle.Bytes(name, now)
+	if err != nil {
+		return err
+	}
+	if err := output.WriteFile(path, data, output.DefaultFileOptions()); err != nil {
+		return fmt.Errorf("failed to write debug bundle: %w", err)
+	}
+	return nil
+}
+
+// doctorSystemInfo describes the jankey build, the platform and the tools
+// jankey runs
+func doctorSystemInfo() []byte {
+	var b bytes.Buffer
+
+	version := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+	}
+	fmt.Fprintf(&b, "jankey: %s\n", version)
+	fmt.Fprintf(&b, "go: %s\n", runtime.Version())
+	fmt.Fprintf(&b, "platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+
+	for _, tool := range append(pass.Backends(), "gpg", "gpg-agent", "age") {
+		toolPath, err := exec.LookPath(tool)
+		if err != nil {
+			toolPath = "not found"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", tool, toolPath)
+	}
+
+	return b.Bytes()
+}
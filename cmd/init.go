@@ -2,16 +2,339 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/ironicbadger/jankey/internal/config"
+	"github.com/ironicbadger/jankey/internal/credential"
+	"github.com/ironicbadger/jankey/internal/keyring"
+	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/ironicbadger/jankey/internal/oauth"
 	"github.com/ironicbadger/jankey/internal/pass"
+	"github.com/ironicbadger/jankey/internal/tailscale"
+	"github.com/ironicbadger/jankey/internal/verify"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
 )
 
-func runInitWizard() error {
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the config file, interactively or from flags",
+	Long: `Create the config file.
+
+By default init runs the interactive configuration wizard, the same as
+jankey --init. With --non-interactive every wizard choice is taken from flags
+or an answers file instead, for provisioning with tools such as Ansible.
+
+An answers file is YAML or JSON with these keys, all optional:
+
+  auth_method: api_key            # or oauth
+  credential_store: pass          # or keyring
+  pass_backend: auto              # or pass, gopass or passage
+  pass_path_api_key: tailscale/api-key
+  pass_path_client_id: tailscale/oauth-client-id
+  pass_path_client_secret: tailscale/oauth-client-secret
+  store_credentials: false
+  ephemeral: false
+  reusable: false
+  preauthorized: true
+  expiry_days: 7
+  tags: ["tag:container"]
+
+With credential_store: keyring the pass_path_* names are keyring item names
+instead, as in the wizard. Flags override the answers file.
+--store-credentials stores the credentials from TS_API_KEY, or
+TS_OAUTH_CLIENT_ID and TS_OAUTH_CLIENT_SECRET, in the credential store.`,
+	Args: cobra.NoArgs,
+	RunE: runInit,
+}
+
+var (
+	initNonInteractive bool
+	initAnswersFile    string
+	initPrint          bool
+	initForce          bool
+	initFlagAnswers    initAnswers
+	initTags           string
+)
+
+// initAnswers are the wizard choices for a non-interactive init
+type initAnswers struct {
+	AuthMethod           string   `yaml:"auth_method"`
+	CredentialStore      string   `yaml:"credential_store"`
+	PassBackend          string   `yaml:"pass_backend"`
+	PassPathAPIKey       string   `yaml:"pass_path_api_key"`
+	PassPathClientID     string   `yaml:"pass_path_client_id"`
+	PassPathClientSecret string   `yaml:"pass_path_client_secret"`
+	StoreCredentials     bool     `yaml:"store_credentials"`
+	Ephemeral            bool     `yaml:"ephemeral"`
+	Reusable             bool     `yaml:"reusable"`
+	Preauthorized        bool     `yaml:"preauthorized"`
+	ExpiryDays           int      `yaml:"expiry_days"`
+	Tags                 []string `yaml:"tags"`
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	defaults := config.GetDefaultConfig()
+	f := initCmd.Flags()
+	f.BoolVar(&initNonInteractive, "non-interactive", false, "take every choice from flags or --answers instead of prompting")
+	f.StringVar(&initAnswersFile, "answers", "", "YAML or JSON file of wizard answers (implies --non-interactive)")
+	f.BoolVar(&initPrint, "print", false, "print the config to stdout instead of writing it")
+	f.BoolVar(&initForce, "force", false, "overwrite an existing config file, and existing stored credentials")
+	f.StringVar(&initFlagAnswers.AuthMethod, "auth-method", models.AuthMethodAPIKey, "authentication method: api_key or oauth")
+	f.StringVar(&initFlagAnswers.CredentialStore, "credential-store", credentialStorePass, "where credentials are kept: pass or keyring")
+	f.StringVar(&initFlagAnswers.PassBackend, "pass-backend", pass.BackendAuto, "password store tool: auto, pass, gopass or passage")
+	f.StringVar(&initFlagAnswers.PassPathAPIKey, "pass-path-api-key", defaults.APIKey.PassPathAPIKey, "pass path, or keyring item name, of the API key")
+	f.StringVar(&initFlagAnswers.PassPathClientID, "pass-path-client-id", defaults.OAuth.PassPathClientID, "pass path, or keyring item name, of the OAuth client ID")
+	f.StringVar(&initFlagAnswers.PassPathClientSecret, "pass-path-client-secret", defaults.OAuth.PassPathClientSecret, "pass path, or keyring item name, of the OAuth client secret")
+	f.BoolVar(&initFlagAnswers.StoreCredentials, "store-credentials", false, "store credentials from TS_API_KEY or TS_OAUTH_CLIENT_ID and TS_OAUTH_CLIENT_SECRET in the credential store")
+	f.BoolVar(&initFlagAnswers.Ephemeral, "ephemeral", defaults.AuthKeyDefaults.Ephemeral, "make keys ephemeral by default")
+	f.BoolVar(&initFlagAnswers.Reusable, "reusable", defaults.AuthKeyDefaults.Reusable, "make keys reusable by default")
+	f.BoolVar(&initFlagAnswers.Preauthorized, "preauthorized", defaults.AuthKeyDefaults.Preauthorized, "pre-authorize devices by default")
+	f.IntVar(&initFlagAnswers.ExpiryDays, "expiry-days", defaults.AuthKeyDefaults.ExpiryDays, "default key expiry in days (1-90)")
+	f.StringVar(&initTags, "tags", "", "comma-separated default tags (required for OAuth, default tag:container)")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	if initAnswersFile != "" {
+		initNonInteractive = true
+	}
+	if !initNonInteractive {
+		if initPrint {
+			return fmt.Errorf("--print requires --non-interactive")
+		}
+		return runInitWizard(cmd.Context())
+	}
+
+	answers, err := loadInitAnswers(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := newInitConfig(answers)
+	if err != nil {
+		return err
+	}
+
+	data, err := config.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	if initPrint {
+		if answers.StoreCredentials {
+			return fmt.Errorf("--store-credentials cannot be used with --print")
+		}
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	configPath, err := resolveConfigPath()
+	if err != nil {
+		return err
+	}
+
+	if config.ConfigExists(configPath) && !initForce {
+		return fmt.Errorf("config file already exists at %s: use --force to overwrite it", configPath)
+	}
+
+	if answers.StoreCredentials {
+		if err := storeInitCredentials(cmd.Context(), cfg, answers.AuthMethod == models.AuthMethodOAuth); err != nil {
+			return err
+		}
+	}
+
+	if err := config.Save(cfg, configPath); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	logger.Info("configuration saved", "path", configPath)
+	return nil
+}
+
+// loadInitAnswers reads --answers, if given, over the defaults and applies
+// the flags that were set
+func loadInitAnswers(cmd *cobra.Command) (initAnswers, error) {
+	defaults := config.GetDefaultConfig()
+	answers := initAnswers{
+		AuthMethod:           models.AuthMethodAPIKey,
+		CredentialStore:      credentialStorePass,
+		PassBackend:          pass.BackendAuto,
+		PassPathAPIKey:       defaults.APIKey.PassPathAPIKey,
+		PassPathClientID:     defaults.OAuth.PassPathClientID,
+		PassPathClientSecret: defaults.OAuth.PassPathClientSecret,
+		Ephemeral:            defaults.AuthKeyDefaults.Ephemeral,
+		Reusable:             defaults.AuthKeyDefaults.Reusable,
+		Preauthorized:        defaults.AuthKeyDefaults.Preauthorized,
+		ExpiryDays:           defaults.AuthKeyDefaults.ExpiryDays,
+	}
+
+	if initAnswersFile != "" {
+		data, err := os.ReadFile(initAnswersFile)
+		if err != nil {
+			return answers, fmt.Errorf("failed to read answers file: %w", err)
+		}
+		if err := parseInitAnswers(data, &answers); err != nil {
+			return answers, fmt.Errorf("failed to parse answers file %s: %w", initAnswersFile, err)
+		}
+	}
+
+	flags := cmd.Flags()
+	set := func(name string, apply func()) {
+		if flags.Changed(name) {
+			apply()
+		}
+	}
+	set("auth-method", func() { answers.AuthMethod = initFlagAnswers.AuthMethod })
+	set("credential-store", func() { answers.CredentialStore = initFlagAnswers.CredentialStore })
+	set("pass-backend", func() { answers.PassBackend = initFlagAnswers.PassBackend })
+	set("pass-path-api-key", func() { answers.PassPathAPIKey = initFlagAnswers.PassPathAPIKey })
+	set("pass-path-client-id", func() { answers.PassPathClientID = initFlagAnswers.PassPathClientID })
+	set("pass-path-client-secret", func() { answers.PassPathClientSecret = initFlagAnswers.PassPathClientSecret })
+	set("store-credentials", func() { answers.StoreCredentials = initFlagAnswers.StoreCredentials })
+	set("ephemeral", func() { answers.Ephemeral = initFlagAnswers.Ephemeral })
+	set("reusable", func() { answers.Reusable = initFlagAnswers.Reusable })
+	set("preauthorized", func() { answers.Preauthorized = initFlagAnswers.Preauthorized })
+	set("expiry-days", func() { answers.ExpiryDays = initFlagAnswers.ExpiryDays })
+	set("tags", func() { answers.Tags = parseTags(initTags) })
+
+	return answers, nil
+}
+
+// parseInitAnswers decodes a YAML or JSON answers file over answers,
+// rejecting unknown keys
+func parseInitAnswers(data []byte, answers *initAnswers) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(answers); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// newInitConfig builds the config the wizard would save for the same
+// choices
+func newInitConfig(answers initAnswers) (*models.Config, error) {
+	cfg := config.GetDefaultConfig()
+
+	if answers.AuthMethod != models.AuthMethodAPIKey && answers.AuthMethod != models.AuthMethodOAuth {
+		return nil, fmt.Errorf("invalid auth method '%s': must be '%s' or '%s'", answers.AuthMethod, models.AuthMethodAPIKey, models.AuthMethodOAuth)
+	}
+	if answers.AuthMethod == models.AuthMethodOAuth {
+		cfg.AuthMethod = models.AuthMethodOAuth
+	}
+
+	switch answers.CredentialStore {
+	case "", credentialStorePass, credentialStoreKeyring:
+	default:
+		return nil, fmt.Errorf("invalid credential store '%s': must be '%s' or '%s'", answers.CredentialStore, credentialStorePass, credentialStoreKeyring)
+	}
+	if answers.PassBackend != pass.BackendAuto {
+		cfg.Pass.Backend = answers.PassBackend
+	}
+
+	if answers.PassPathAPIKey != "" {
+		cfg.APIKey.PassPathAPIKey = answers.PassPathAPIKey
+	}
+	if answers.PassPathClientID != "" {
+		cfg.OAuth.PassPathClientID = answers.PassPathClientID
+	}
+	if answers.PassPathClientSecret != "" {
+		cfg.OAuth.PassPathClientSecret = answers.PassPathClientSecret
+	}
+	if answers.CredentialStore == credentialStoreKeyring {
+		useKeyringNames(cfg)
+	}
+
+	cfg.AuthKeyDefaults.Ephemeral = answers.Ephemeral
+	cfg.AuthKeyDefaults.Reusable = answers.Reusable
+	cfg.AuthKeyDefaults.Preauthorized = answers.Preauthorized
+	cfg.AuthKeyDefaults.ExpiryDays = answers.ExpiryDays
+
+	// Tags are normalized as the wizard does, and required for OAuth
+	cfg.AuthKeyDefaults.Tags = parseTags(strings.Join(answers.Tags, ","))
+	if answers.AuthMethod == models.AuthMethodOAuth && len(cfg.AuthKeyDefaults.Tags) == 0 {
+		logger.Warn("no tags specified for OAuth, using default", "tags", "tag:container")
+		cfg.AuthKeyDefaults.Tags = []string{"tag:container"}
+	}
+
+	if err := config.Validate(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// storeInitCredentials stores the credentials from the environment in the
+// keyring if the config names keyring items, or otherwise in pass at the
+// configured paths
+func storeInitCredentials(ctx context.Context, cfg *models.Config, useOAuth bool) error {
+	store := &credentialStore{}
+	if keyringUsed(cfg) {
+		keyringClient, err := keyring.New()
+		if err != nil {
+			return fmt.Errorf("--store-credentials requires a keyring: %w", err)
+		}
+		defer keyringClient.Close()
+		store.keyring = keyringClient
+	} else {
+		passClient, err := pass.New(cfg.Pass.Backend)
+		if err != nil {
+			return fmt.Errorf("--store-credentials requires pass: %w", err)
+		}
+		store.pass = passClient
+	}
+
+	type secret struct {
+		envVar                string
+		passPath, keyringName string
+		name                  string
+	}
+	secrets := []secret{{"TS_API_KEY", cfg.APIKey.PassPathAPIKey, cfg.APIKey.KeyringAPIKey, "API key"}}
+	if useOAuth {
+		secrets = []secret{
+			{"TS_OAUTH_CLIENT_ID", cfg.OAuth.PassPathClientID, cfg.OAuth.KeyringClientID, "OAuth client ID"},
+			{"TS_OAUTH_CLIENT_SECRET", cfg.OAuth.PassPathClientSecret, cfg.OAuth.KeyringClientSecret, "OAuth client secret"},
+		}
+	}
+
+	for _, s := range secrets {
+		value := os.Getenv(s.envVar)
+		if value == "" {
+			return fmt.Errorf("--store-credentials requires %s to be set", s.envVar)
+		}
+
+		location := *store.location(&s.passPath, &s.keyringName)
+		if !initForce && store.exists(ctx, location) {
+			return fmt.Errorf("%s already exists in %s at '%s': use --force to overwrite it", s.name, store, location)
+		}
+		if err := store.insert(ctx, location, s.name, value, initForce); err != nil {
+			return fmt.Errorf("failed to store %s in %s: %w", s.name, store, err)
+		}
+
+		logger.Info("credential stored", "credential", s.name, "store", store.String(), "location", location)
+	}
+
+	return nil
+}
+
+func runInitWizard(ctx context.Context) error {
+	// The wizard blocks reading stdin and cannot observe context
+	// cancellation, so let Ctrl-C terminate the process as usual
+	signal.Reset(os.Interrupt, syscall.SIGTERM)
+
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Println("╔════════════════════════════════════════════════════════════════╗")
@@ -62,13 +385,37 @@ func runInitWizard() error {
 	fmt.Println("─────────────────────────────")
 	fmt.Println()
 
-	usePass := false
-	passClient, err := pass.New()
+	// store is nil if credentials are set in environment variables
+	var store *credentialStore
+	passClient, err := pass.New(pass.BackendAuto)
 	if err != nil {
 		fmt.Println("⚠  Pass (password store) is not installed or not available.")
 		fmt.Println("   Pass is recommended for secure credential storage.")
 		fmt.Println("   Install: https://www.passwordstore.org/")
 		fmt.Println()
+	} else {
+		fmt.Printf("✓ %s is installed and available\n", passClient.Name())
+		fmt.Println()
+		if promptYesNo(reader, "Do you want to store credentials in pass?", true) {
+			store = &credentialStore{pass: passClient}
+		}
+		fmt.Println()
+	}
+
+	// Without pass, offer the desktop keyring
+	if store == nil {
+		if keyringClient, err := keyring.New(); err == nil {
+			defer keyringClient.Close()
+			fmt.Println("✓ A Secret Service keyring (GNOME Keyring or KWallet) is available")
+			fmt.Println()
+			if promptYesNo(reader, "Do you want to store credentials in the keyring?", true) {
+				store = &credentialStore{keyring: keyringClient}
+			}
+			fmt.Println()
+		}
+	}
+
+	if store == nil && passClient == nil {
 		if useAPIKey {
 			fmt.Println("You can use the TS_API_KEY environment variable instead.")
 		} else {
@@ -77,14 +424,21 @@ func runInitWizard() error {
 			fmt.Println("  - TS_OAUTH_CLIENT_SECRET")
 		}
 		fmt.Println()
-	} else {
-		fmt.Println("✓ Pass is installed and available")
-		fmt.Println()
-		usePass = promptYesNo(reader, "Do you want to store credentials in pass?", true)
-		fmt.Println()
 	}
 
 	cfg := config.GetDefaultConfig()
+	if !useAPIKey {
+		cfg.AuthMethod = models.AuthMethodOAuth
+	}
+	if store != nil && store.pass != nil && passClient.Name() != pass.BackendPass {
+		cfg.Pass.Backend = passClient.Name()
+	}
+	if store != nil && store.keyring != nil {
+		useKeyringNames(cfg)
+	}
+
+	// Credentials entered below, verified before saving
+	var creds verify.Options
 
 	// Step 3: Configure credentials
 	if useAPIKey {
@@ -97,22 +451,22 @@ func runInitWizard() error {
 		fmt.Println("⚠  API keys expire after 90 days and will need to be regenerated.")
 		fmt.Println()
 
-		if usePass {
-			fmt.Printf("Enter the pass path for API key [%s]: ", cfg.APIKey.PassPathAPIKey)
-			apiKeyPath := readLine(reader)
-			if apiKeyPath != "" {
-				cfg.APIKey.PassPathAPIKey = apiKeyPath
+		if store != nil {
+			apiKeyPath := store.location(&cfg.APIKey.PassPathAPIKey, &cfg.APIKey.KeyringAPIKey)
+			fmt.Printf("Enter the %s for API key [%s]: ", store.locationName(), *apiKeyPath)
+			if path := readLine(reader); path != "" {
+				*apiKeyPath = path
 			}
 
 			fmt.Println()
-			if promptYesNo(reader, "Do you want to store the API key in pass now?", true) {
-				fmt.Print("Enter API key: ")
-				apiKey := readLine(reader)
+			if promptYesNo(reader, fmt.Sprintf("Do you want to store the API key in %s now?", store), true) {
+				apiKey := readSecret(reader, "Enter API key: ")
+				creds.APIKey = apiKey
 
-				if err := passClient.Insert(cfg.APIKey.PassPathAPIKey, apiKey); err != nil {
-					fmt.Printf("Warning: failed to store API key in pass: %v\n", err)
+				if err := store.insert(ctx, *apiKeyPath, "API key", apiKey, false); err != nil {
+					fmt.Printf("Warning: failed to store API key in %s: %v\n", store, err)
 				} else {
-					fmt.Println("✓ API key stored in pass")
+					fmt.Printf("✓ API key stored in %s\n", store)
 				}
 			}
 		} else {
@@ -130,36 +484,35 @@ func runInitWizard() error {
 		fmt.Println("  - auth_keys (or devices:write)")
 		fmt.Println()
 
-		if usePass {
-			fmt.Printf("Enter the pass path for OAuth client ID [%s]: ", cfg.OAuth.PassPathClientID)
-			clientIDPath := readLine(reader)
-			if clientIDPath != "" {
-				cfg.OAuth.PassPathClientID = clientIDPath
+		if store != nil {
+			clientIDPath := store.location(&cfg.OAuth.PassPathClientID, &cfg.OAuth.KeyringClientID)
+			fmt.Printf("Enter the %s for OAuth client ID [%s]: ", store.locationName(), *clientIDPath)
+			if path := readLine(reader); path != "" {
+				*clientIDPath = path
 			}
 
-			fmt.Printf("Enter the pass path for OAuth client secret [%s]: ", cfg.OAuth.PassPathClientSecret)
-			clientSecretPath := readLine(reader)
-			if clientSecretPath != "" {
-				cfg.OAuth.PassPathClientSecret = clientSecretPath
+			clientSecretPath := store.location(&cfg.OAuth.PassPathClientSecret, &cfg.OAuth.KeyringClientSecret)
+			fmt.Printf("Enter the %s for OAuth client secret [%s]: ", store.locationName(), *clientSecretPath)
+			if path := readLine(reader); path != "" {
+				*clientSecretPath = path
 			}
 
 			fmt.Println()
-			if promptYesNo(reader, "Do you want to store the credentials in pass now?", true) {
-				fmt.Print("Enter OAuth client ID: ")
-				clientID := readLine(reader)
-				fmt.Print("Enter OAuth client secret: ")
-				clientSecret := readLine(reader)
-
-				if err := passClient.Insert(cfg.OAuth.PassPathClientID, clientID); err != nil {
-					fmt.Printf("Warning: failed to store client ID in pass: %v\n", err)
+			if promptYesNo(reader, fmt.Sprintf("Do you want to store the credentials in %s now?", store), true) {
+				clientID := readSecret(reader, "Enter OAuth client ID: ")
+				clientSecret := readSecret(reader, "Enter OAuth client secret: ")
+				creds.ClientID, creds.ClientSecret = clientID, clientSecret
+
+				if err := store.insert(ctx, *clientIDPath, "OAuth client ID", clientID, false); err != nil {
+					fmt.Printf("Warning: failed to store client ID in %s: %v\n", store, err)
 				} else {
-					fmt.Println("✓ Client ID stored in pass")
+					fmt.Printf("✓ Client ID stored in %s\n", store)
 				}
 
-				if err := passClient.Insert(cfg.OAuth.PassPathClientSecret, clientSecret); err != nil {
-					fmt.Printf("Warning: failed to store client secret in pass: %v\n", err)
+				if err := store.insert(ctx, *clientSecretPath, "OAuth client secret", clientSecret, false); err != nil {
+					fmt.Printf("Warning: failed to store client secret in %s: %v\n", store, err)
 				} else {
-					fmt.Println("✓ Client secret stored in pass")
+					fmt.Printf("✓ Client secret stored in %s\n", store)
 				}
 			}
 		} else {
@@ -246,8 +599,18 @@ func runInitWizard() error {
 		fmt.Println()
 	}
 
-	// Step 6: Save configuration
-	fmt.Println("Step 6: Save Configuration")
+	// Step 6: Verify credentials
+	fmt.Println("Step 6: Verify Credentials")
+	fmt.Println("──────────────────────────")
+	fmt.Println()
+
+	if !verifyWizardCredentials(ctx, reader, cfg, useAPIKey, passClient, store, creds) {
+		fmt.Println("Configuration wizard cancelled.")
+		return nil
+	}
+
+	// Step 7: Save configuration
+	fmt.Println("Step 7: Save Configuration")
 	fmt.Println("──────────────────────────")
 	fmt.Println()
 	fmt.Printf("Configuration will be saved to: %s\n", configPath)
@@ -267,18 +630,307 @@ func runInitWizard() error {
 	fmt.Println()
 	fmt.Println("Next steps:")
 	if useAPIKey {
-		fmt.Println("  1. Ensure your API key is properly stored in pass or TS_API_KEY")
+		fmt.Println("  1. Ensure your API key is properly stored in pass, the keyring or TS_API_KEY")
 		fmt.Println("  2. Run 'jankey' to generate your first auth key")
 	} else {
 		fmt.Println("  1. Ensure your OAuth credentials are properly stored")
 		fmt.Println("  2. Verify your Tailscale ACL includes the configured tags")
-		fmt.Println("  3. Run 'jankey --use-oauth' to generate your first auth key")
+		fmt.Println("  3. Run 'jankey' to generate your first auth key")
 	}
 	fmt.Println()
 
 	return nil
 }
 
+// verifyWizardCredentials checks the credentials entered in the wizard, or
+// found in their sources, against the Tailscale API and offers to fix the
+// problems found. It reports whether to save the configuration.
+func verifyWizardCredentials(ctx context.Context, reader *bufio.Reader, cfg *models.Config, useAPIKey bool, passClient *pass.Client, store *credentialStore, creds verify.Options) bool {
+	credOpts := credential.Options{Pass: passClient, Logger: logger}
+	if store != nil {
+		credOpts.Keyring = store.keyring
+	}
+
+	if !lookupWizardCredentials(ctx, reader, cfg, useAPIKey, credOpts, &creds) {
+		fmt.Println("⚠  Skipping verification: run 'jankey' to check the credentials later.")
+		return true
+	}
+
+	for {
+		opts, err := resolveAPIOptions(cfg)
+		if err != nil {
+			fmt.Printf("⚠  Skipping verification: %v\n", err)
+			return true
+		}
+		creds.API = opts
+		creds.Tags = cfg.AuthKeyDefaults.Tags
+
+		fmt.Println("Verifying credentials with the Tailscale API...")
+		result := verify.Verify(ctx, creds)
+		printVerifyResult(result)
+		fmt.Println()
+
+		if result.OK() {
+			return true
+		}
+
+		if !fixVerifyProblems(ctx, reader, cfg, store, &creds, result) {
+			return promptYesNo(reader, "Save configuration anyway?", false)
+		}
+		fmt.Println()
+	}
+}
+
+// lookupWizardCredentials fills in the credentials not entered in the
+// wizard from their configured sources, as key generation does, or prompts
+// for them. It reports whether there are credentials to verify.
+func lookupWizardCredentials(ctx context.Context, reader *bufio.Reader, cfg *models.Config, useAPIKey bool, credOpts credential.Options, creds *verify.Options) bool {
+	type secret struct {
+		value       *string
+		chain       *credential.Chain
+		entryPrompt string
+	}
+
+	var secrets []secret
+	if useAPIKey {
+		chain, err := credential.APIKey(cfg, credOpts)
+		if err != nil {
+			fmt.Printf("⚠  %v\n", err)
+			return false
+		}
+		secrets = []secret{{&creds.APIKey, chain, "Enter API key: "}}
+	} else {
+		idChain, secretChain, err := credential.OAuthClient(cfg, credOpts)
+		if err != nil {
+			fmt.Printf("⚠  %v\n", err)
+			return false
+		}
+		secrets = []secret{
+			{&creds.ClientID, idChain, "Enter OAuth client ID: "},
+			{&creds.ClientSecret, secretChain, "Enter OAuth client secret: "},
+		}
+	}
+
+	for _, s := range secrets {
+		if *s.value != "" {
+			continue
+		}
+
+		value, provider, err := s.chain.Get(ctx)
+		if err == nil {
+			fmt.Printf("Using the %s from %s\n", s.chain.Name, provider)
+			*s.value = value
+			continue
+		}
+
+		fmt.Printf("⚠  %v\n", err)
+		if !promptYesNo(reader, fmt.Sprintf("Enter the %s to verify it (it will not be saved)?", s.chain.Name), true) {
+			return false
+		}
+		*s.value = readSecret(reader, s.entryPrompt)
+	}
+
+	return true
+}
+
+// printVerifyResult shows what was verified and the problems found
+func printVerifyResult(result *verify.Result) {
+	tailnet := result.Tailnet
+	if tailnet == tailscale.DefaultTailnet {
+		tailnet = "the tailnet of the " + result.Credential
+	}
+
+	if result.Authenticated {
+		fmt.Printf("✓ %s accepted\n", result.Credential)
+	}
+	if len(result.Scopes) > 0 && !result.Has(verify.ProblemScope) {
+		fmt.Printf("✓ OAuth scopes: %s\n", strings.Join(result.Scopes, ", "))
+	}
+	if result.Listed {
+		fmt.Printf("✓ Tailnet: %s (%d auth keys)\n", tailnet, result.AuthKeys)
+	}
+	for _, tag := range slices.Sorted(maps.Keys(result.TagOwners)) {
+		fmt.Printf("✓ %s is owned by %s\n", tag, strings.Join(result.TagOwners[tag], ", "))
+	}
+	for _, p := range result.Problems {
+		if p.Kind == verify.ProblemPolicy {
+			fmt.Printf("⚠  %s\n", p.Message)
+		} else {
+			fmt.Printf("✗ %s\n", p.Message)
+		}
+	}
+}
+
+// fixVerifyProblems offers to fix the problems found by verification. It
+// reports whether anything changed, so verification should be repeated.
+func fixVerifyProblems(ctx context.Context, reader *bufio.Reader, cfg *models.Config, store *credentialStore, creds *verify.Options, result *verify.Result) bool {
+	switch {
+	case result.Has(verify.ProblemCredential), result.Has(verify.ProblemScope):
+		if result.Has(verify.ProblemScope) {
+			fmt.Println("Create a credential that may manage auth keys at:")
+			if creds.APIKey != "" {
+				fmt.Println("  https://login.tailscale.com/admin/settings/keys")
+			} else {
+				fmt.Printf("  https://login.tailscale.com/admin/settings/oauth (scopes: %s)\n", strings.Join(oauth.AuthKeyScopes, ", "))
+			}
+		}
+		if !promptYesNo(reader, fmt.Sprintf("Enter a different %s?", result.Credential), true) {
+			return false
+		}
+		reenterWizardCredentials(ctx, reader, cfg, store, creds)
+		return true
+
+	case result.Has(verify.ProblemTailnet):
+		if tailnet != "" || os.Getenv("TS_TAILNET") != "" {
+			fmt.Println("The tailnet is set by --tailnet or TS_TAILNET; change it there.")
+			return false
+		}
+		fmt.Printf("Enter the tailnet name, or %s for the tailnet of the %s [%s]: ", tailscale.DefaultTailnet, result.Credential, tailscale.DefaultTailnet)
+		name := readLine(reader)
+		if name == "" || name == tailscale.DefaultTailnet {
+			name = ""
+		}
+		if name == cfg.API.Tailnet {
+			return false
+		}
+		cfg.API.Tailnet = name
+		return true
+
+	case result.Has(verify.ProblemTag):
+		fmt.Println("Tags must be defined in the tagOwners section of the tailnet policy file:")
+		fmt.Println("  https://login.tailscale.com/admin/acls")
+		fmt.Println()
+		fmt.Print("Enter replacement default tags (comma-separated), or leave empty to remove the undefined tags: ")
+		if tagsInput := readLine(reader); tagsInput != "" {
+			cfg.AuthKeyDefaults.Tags = parseTags(tagsInput)
+			return true
+		}
+
+		tags := removeTags(cfg.AuthKeyDefaults.Tags, result.Tags())
+		if len(tags) == 0 && creds.ClientID != "" {
+			fmt.Println("⚠  OAuth requires at least one tag, keeping the tags.")
+			return false
+		}
+		cfg.AuthKeyDefaults.Tags = tags
+		return true
+
+	case result.Has(verify.ProblemAPI):
+		return promptYesNo(reader, "Try again?", true)
+	}
+
+	// The policy file could not be read, which does not prevent creating
+	// keys
+	return false
+}
+
+// reenterWizardCredentials prompts for new credentials, updating them in
+// the store the wizard keeps credentials in, if any
+func reenterWizardCredentials(ctx context.Context, reader *bufio.Reader, cfg *models.Config, store *credentialStore, creds *verify.Options) {
+	type secret struct {
+		value                 *string
+		passPath, keyringName *string
+		name, entryPrompt     string
+	}
+	secrets := []secret{{&creds.APIKey, &cfg.APIKey.PassPathAPIKey, &cfg.APIKey.KeyringAPIKey, "API key", "Enter API key: "}}
+	if creds.APIKey == "" {
+		secrets = []secret{
+			{&creds.ClientID, &cfg.OAuth.PassPathClientID, &cfg.OAuth.KeyringClientID, "OAuth client ID", "Enter OAuth client ID: "},
+			{&creds.ClientSecret, &cfg.OAuth.PassPathClientSecret, &cfg.OAuth.KeyringClientSecret, "OAuth client secret", "Enter OAuth client secret: "},
+		}
+	}
+
+	for _, s := range secrets {
+		*s.value = readSecret(reader, s.entryPrompt)
+
+		if store == nil {
+			continue
+		}
+		if err := store.insert(ctx, *store.location(s.passPath, s.keyringName), s.name, *s.value, true); err != nil {
+			fmt.Printf("Warning: failed to store %s in %s: %v\n", s.name, store, err)
+		} else {
+			fmt.Printf("✓ %s updated in %s\n", s.name, store)
+		}
+	}
+	fmt.Println()
+}
+
+// Credential stores offered by init
+const (
+	credentialStorePass    = "pass"
+	credentialStoreKeyring = "keyring"
+)
+
+// credentialStore is where the wizard stores the credentials entered: pass,
+// or the keyring if pass is not used
+type credentialStore struct {
+	pass    *pass.Client
+	keyring *keyring.Client
+}
+
+// String names the store for messages
+func (s *credentialStore) String() string {
+	if s.keyring != nil {
+		return "the keyring"
+	}
+	return s.pass.Name()
+}
+
+// locationName describes where a credential is in the store, for prompts
+func (s *credentialStore) locationName() string {
+	if s.keyring != nil {
+		return "keyring item name"
+	}
+	return "pass path"
+}
+
+// location returns the config field for where a credential is in the store
+func (s *credentialStore) location(passPath, keyringName *string) *string {
+	if s.keyring != nil {
+		return keyringName
+	}
+	return passPath
+}
+
+// exists reports whether a credential is stored at location
+func (s *credentialStore) exists(ctx context.Context, location string) bool {
+	if s.keyring != nil {
+		_, err := s.keyring.Get(ctx, location)
+		return err == nil
+	}
+	return s.pass.Exists(location)
+}
+
+// insert stores a credential, replacing an existing one if replace is set.
+// The keyring always replaces items.
+func (s *credentialStore) insert(ctx context.Context, location, name, value string, replace bool) error {
+	if s.keyring != nil {
+		return s.keyring.Set(ctx, location, "Tailscale "+name, value)
+	}
+	if replace {
+		return s.pass.Overwrite(location, value)
+	}
+	return s.pass.Insert(location, value)
+}
+
+// useKeyringNames moves the default pass paths of the credentials to
+// keyring item names, for storing credentials in the keyring
+func useKeyringNames(cfg *models.Config) {
+	cfg.APIKey.KeyringAPIKey, cfg.APIKey.PassPathAPIKey = cfg.APIKey.PassPathAPIKey, ""
+	cfg.OAuth.KeyringClientID, cfg.OAuth.PassPathClientID = cfg.OAuth.PassPathClientID, ""
+	cfg.OAuth.KeyringClientSecret, cfg.OAuth.PassPathClientSecret = cfg.OAuth.PassPathClientSecret, ""
+}
+
+// removeTags returns tags without those in remove
+func removeTags(tags, remove []string) []string {
+	kept := []string{}
+	for _, tag := range tags {
+		if !slices.Contains(remove, tag) {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
 func promptYesNo(reader *bufio.Reader, prompt string, defaultYes bool) bool {
 	defaultStr := "y/N"
 	if defaultYes {
@@ -299,3 +951,21 @@ func readLine(reader *bufio.Reader) string {
 	line, _ := reader.ReadString('\n')
 	return strings.TrimSpace(line)
 }
+
+// readSecret prompts for a secret, without echoing it when stdin is a
+// terminal
+func readSecret(reader *bufio.Reader, prompt string) string {
+	fmt.Print(prompt)
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return readLine(reader)
+	}
+
+	secret, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(secret))
+}
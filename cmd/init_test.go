@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ironicbadger/jankey/internal/config"
+	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/ironicbadger/jankey/internal/pass"
+)
+
+func TestParseInitAnswers(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    initAnswers
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			data: "auth_method: oauth\ntags: [ci]\nexpiry_days: 30\n",
+			want: initAnswers{AuthMethod: "oauth", Tags: []string{"ci"}, ExpiryDays: 30, Preauthorized: true},
+		},
+		{
+			name: "json",
+			data: `{"reusable": true, "pass_path_api_key": "infra/ts", "credential_store": "keyring", "pass_backend": "passage"}`,
+			want: initAnswers{AuthMethod: "api_key", CredentialStore: "keyring", PassBackend: "passage", Reusable: true, PassPathAPIKey: "infra/ts", ExpiryDays: 7, Preauthorized: true},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: initAnswers{AuthMethod: "api_key", ExpiryDays: 7, Preauthorized: true},
+		},
+		{
+			name:    "unknown key",
+			data:    "expiry: 30\n",
+			wantErr: "field expiry not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answers := initAnswers{AuthMethod: "api_key", ExpiryDays: 7, Preauthorized: true}
+			err := parseInitAnswers([]byte(tt.data), &answers)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseInitAnswers() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseInitAnswers() error = %v", err)
+			}
+
+			if answers.AuthMethod != tt.want.AuthMethod || answers.ExpiryDays != tt.want.ExpiryDays ||
+				answers.Reusable != tt.want.Reusable || answers.Preauthorized != tt.want.Preauthorized ||
+				answers.PassPathAPIKey != tt.want.PassPathAPIKey || answers.CredentialStore != tt.want.CredentialStore ||
+				answers.PassBackend != tt.want.PassBackend || strings.Join(answers.Tags, ",") != strings.Join(tt.want.Tags, ",") {
+				t.Errorf("parseInitAnswers() = %+v, want %+v", answers, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInitConfig(t *testing.T) {
+	defaults := config.GetDefaultConfig()
+	base := initAnswers{
+		AuthMethod:    models.AuthMethodAPIKey,
+		Preauthorized: true,
+		ExpiryDays:    7,
+	}
+
+	// Default answers give the default config, as the wizard does
+	cfg, err := newInitConfig(base)
+	if err != nil {
+		t.Fatalf("newInitConfig() error = %v", err)
+	}
+	got, _ := config.Marshal(cfg)
+	want, _ := config.Marshal(defaults)
+	if string(got) != string(want) {
+		t.Errorf("newInitConfig() =\n%s\nwant\n%s", got, want)
+	}
+
+	oauth := base
+	oauth.AuthMethod = models.AuthMethodOAuth
+	oauth.PassPathClientID = "infra/id"
+	oauth.Tags = []string{"ci", "tag:web"}
+	cfg, err = newInitConfig(oauth)
+	if err != nil {
+		t.Fatalf("newInitConfig(oauth) error = %v", err)
+	}
+	if cfg.OAuth.PassPathClientID != "infra/id" || cfg.OAuth.PassPathClientSecret != defaults.OAuth.PassPathClientSecret ||
+		strings.Join(cfg.AuthKeyDefaults.Tags, ",") != "tag:ci,tag:web" {
+		t.Errorf("newInitConfig(oauth) = %+v", cfg)
+	}
+	if data, _ := config.Marshal(cfg); !strings.Contains(string(data), "auth_method: oauth") {
+		t.Errorf("newInitConfig(oauth) config =\n%s\nwant auth_method: oauth", data)
+	}
+
+	oauth.Tags = nil
+	if cfg, err := newInitConfig(oauth); err != nil || strings.Join(cfg.AuthKeyDefaults.Tags, ",") != "tag:container" {
+		t.Errorf("newInitConfig(oauth without tags) = %+v, %v, want tag:container", cfg, err)
+	}
+
+	// The keyring takes the credential names, as in the wizard
+	keyringAnswers := base
+	keyringAnswers.CredentialStore = credentialStoreKeyring
+	keyringAnswers.PassBackend = pass.BackendGopass
+	keyringAnswers.PassPathAPIKey = "infra/ts"
+	cfg, err = newInitConfig(keyringAnswers)
+	if err != nil {
+		t.Fatalf("newInitConfig(keyring) error = %v", err)
+	}
+	if cfg.APIKey.KeyringAPIKey != "infra/ts" || cfg.APIKey.PassPathAPIKey != "" ||
+		cfg.OAuth.KeyringClientID != defaults.OAuth.PassPathClientID || cfg.Pass.Backend != pass.BackendGopass {
+		t.Errorf("newInitConfig(keyring) = %+v", cfg)
+	}
+
+	for name, answers := range map[string]initAnswers{
+		"invalid credential store": {AuthMethod: models.AuthMethodAPIKey, CredentialStore: "vault", ExpiryDays: 7},
+		"invalid pass backend":     {AuthMethod: models.AuthMethodAPIKey, PassBackend: "keepass", ExpiryDays: 7},
+		"invalid auth method":      {AuthMethod: "password", ExpiryDays: 7},
+		"invalid expiry":           {AuthMethod: models.AuthMethodAPIKey, ExpiryDays: 365},
+	} {
+		if _, err := newInitConfig(answers); err == nil {
+			t.Errorf("newInitConfig(%s) error = nil, want error", name)
+		}
+	}
+}
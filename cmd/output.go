@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/ironicbadger/jankey/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// outputTarget writes a generated key as selected by the output flags
+type outputTarget struct {
+	formatter output.Formatter
+	file      string
+	fileOpts  output.FileOptions
+	updateEnv bool
+}
+
+// newOutputTarget validates the output flags
+func newOutputTarget(cmd *cobra.Command) (*outputTarget, error) {
+	formatter, err := newFormatter(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &outputTarget{
+		formatter: formatter,
+		file:      outputFile,
+		fileOpts:  output.DefaultFileOptions(),
+		updateEnv: updateEnv,
+	}
+
+	if outputFile == "" {
+		if outputOwner != "" || updateEnv {
+			return nil, fmt.Errorf("--output-owner and --update-env require --output-file")
+		}
+		return target, nil
+	}
+
+	if updateEnv {
+		if cmd.Flags().Changed("format") || jsonOutput || outputTemplate != "" {
+			return nil, fmt.Errorf("--update-env cannot be combined with --format, --json or --template")
+		}
+		if err := output.ValidateEnvVar(outputEnvVar); err != nil {
+			return nil, err
+		}
+	}
+
+	if outputOwner != "" {
+		target.fileOpts, err = output.LookupOwner(output.ParseOwner(outputOwner))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return target, nil
+}
+
+// newFormatter returns the formatter selected by --format. --json is an
+// alias for --format json.
+func newFormatter(cmd *cobra.Command) (output.Formatter, error) {
+	if jsonOutput {
+		if cmd.Flags().Changed("format") && outputFormat != "json" {
+			return nil, fmt.Errorf("--json conflicts with --format %s", outputFormat)
+		}
+		outputFormat = "json"
+	}
+
+	if outputTemplate != "" && !cmd.Flags().Changed("format") {
+		outputFormat = "template"
+	}
+
+	return output.New(outputFormat, output.Options{
+		Template: outputTemplate,
+		EnvVar:   outputEnvVar,
+	})
+}
+
+// write outputs the key to stdout or the output file
+func (t *outputTarget) write(resp *models.AuthKeyResponse) error {
+	if t.file == "" {
+		return t.writeStdout(resp)
+	}
+
+	if t.updateEnv {
+		if err := output.UpdateEnvFile(t.file, outputEnvVar, resp.Key, t.fileOpts); err != nil {
+			return err
+		}
+		logger.Info("auth key written", "file", t.file, "var", outputEnvVar, "id", resp.ID)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := t.formatter.Format(&buf, output.NewAuthKeyOutput(resp)); err != nil {
+		return err
+	}
+
+	if err := output.WriteFile(t.file, buf.Bytes(), t.fileOpts); err != nil {
+		return err
+	}
+
+	logger.Info("auth key written", "file", t.file, "id", resp.ID)
+	return nil
+}
+
+func (t *outputTarget) writeStdout(resp *models.AuthKeyResponse) error {
+	if err := t.formatter.Format(os.Stdout, output.NewAuthKeyOutput(resp)); err != nil {
+		return err
+	}
+
+	// Copy the bare key to the clipboard on macOS
+	if outputFormat == "plain" && runtime.GOOS == "darwin" {
+		if err := copyToClipboard(resp.Key); err != nil {
+			logger.Warn("failed to copy to clipboard", "error", err)
+		} else {
+			logger.Debug("auth key copied to clipboard")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ironicbadger/jankey/internal/config"
+	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/ironicbadger/jankey/internal/tailscale"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage configuration profiles",
+	Long: `Manage named profiles for different tailnets and credentials.
+
+Profiles are defined in the profiles section of the config file and applied
+over its top-level settings. The profile used is chosen by --profile, then the
+JANKEY_PROFILE environment variable, then current_profile in the config.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles, marking the one in use",
+	Args:  cobra.NoArgs,
+	RunE:  runProfileList,
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use NAME",
+	Short: "Set the default profile (current_profile) in the config file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProfileUse,
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show [NAME]",
+	Short: "Show the effective settings of a profile (default: the one in use)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runProfileShow,
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd, profileUseCmd, profileShowCmd)
+}
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	cfg, err := loadBaseConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Profiles) == 0 {
+		fmt.Println("No profiles configured.")
+		return nil
+	}
+
+	selected := config.SelectedProfile(cfg, profileName)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CURRENT\tNAME\tAUTH\tTAILNET\tAPI URL")
+	for _, name := range config.ProfileNames(cfg) {
+		resolved, err := config.ApplyProfile(cfg, name)
+		if err != nil {
+			return err
+		}
+
+		current := ""
+		if name == selected {
+			current = "*"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			current,
+			name,
+			authMethodName(resolved),
+			firstNonEmpty(resolved.API.Tailnet, tailscale.DefaultTailnet),
+			firstNonEmpty(resolved.API.BaseURL, tailscale.DefaultBaseURL),
+		)
+	}
+
+	return w.Flush()
+}
+
+func runProfileUse(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	cfg, err := loadBaseConfig()
+	if err != nil {
+		return err
+	}
+
+	if _, err := config.ApplyProfile(cfg, name); err != nil {
+		return err
+	}
+
+	configPath, err := resolveConfigPath()
+	if err != nil {
+		return err
+	}
+
+	if err := config.SetCurrentProfile(configPath, name); err != nil {
+		return err
+	}
+
+	fmt.Printf("Switched to profile %q.\n", name)
+	return nil
+}
+
+func runProfileShow(cmd *cobra.Command, args []string) error {
+	cfg, err := loadBaseConfig()
+	if err != nil {
+		return err
+	}
+
+	name := config.SelectedProfile(cfg, profileName)
+	if len(args) == 1 {
+		name = args[0]
+	}
+	if name == "" {
+		return fmt.Errorf("no profile selected: pass a profile name, --profile or set %s", config.ProfileEnvVar)
+	}
+
+	resolved, err := config.ApplyProfile(cfg, name)
+	if err != nil {
+		return err
+	}
+
+	// Show only the settings a profile affects
+	out := struct {
+		Profile         string                 `yaml:"profile"`
+		AuthMethod      string                 `yaml:"auth_method"`
+		APIKey          models.APIKeyConfig    `yaml:"api_key"`
+		OAuth           models.OAuthConfig     `yaml:"oauth"`
+		API             models.APIConfig       `yaml:"api"`
+		AuthKeyDefaults models.AuthKeyDefaults `yaml:"auth_key_defaults"`
+		Output          models.OutputConfig    `yaml:"output,omitempty"`
+	}{
+		Profile:         name,
+		AuthMethod:      authMethodName(resolved),
+		APIKey:          resolved.APIKey,
+		OAuth:           resolved.OAuth,
+		API:             resolved.API,
+		AuthKeyDefaults: resolved.AuthKeyDefaults,
+		Output:          resolved.Output,
+	}
+
+	data, err := yaml.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile: %w", err)
+	}
+
+	fmt.Print(string(data))
+	return nil
+}
+
+// authMethodName returns the authentication method a config selects
+func authMethodName(cfg *models.Config) string {
+	if cfg.AuthMethod == "" {
+		return models.AuthMethodAPIKey
+	}
+	return cfg.AuthMethod
+}
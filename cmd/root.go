@@ -1,34 +1,64 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
-	"runtime"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
-	"github.com/spf13/cobra"
-	"github.com/ironicbadger/jankey/internal/apikey"
 	"github.com/ironicbadger/jankey/internal/config"
-	"github.com/ironicbadger/jankey/internal/oauth"
+	"github.com/ironicbadger/jankey/internal/keyring"
+	"github.com/ironicbadger/jankey/internal/logging"
+	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/ironicbadger/jankey/internal/output"
 	"github.com/ironicbadger/jankey/internal/pass"
+	"github.com/ironicbadger/jankey/internal/retry"
 	"github.com/ironicbadger/jankey/internal/tailscale"
-	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/spf13/cobra"
 )
 
 var (
 	// Flags
-	cfgFile     string
-	verbose     bool
-	jsonOutput  bool
-	ephemeral   bool
-	reusable    bool
-	expiryDays  int
-	tags        string
-	description string
-	initConfig  bool
-	useOAuth    bool
+	cfgFile        string
+	verbose        bool
+	jsonOutput     bool
+	outputFormat   string
+	outputTemplate string
+	outputEnvVar   string
+	outputFile     string
+	outputOwner    string
+	updateEnv      bool
+	storePass      string
+	storeKeyring   string
+	preset         string
+	profileName    string
+	ephemeral      bool
+	reusable       bool
+	expiryDays     int
+	tags           string
+	description    string
+	initConfig     bool
+	useOAuth       bool
+	apiURL         string
+	tailnet        string
+	maxRetries     int
+	retryBudget    time.Duration
+	timeout        time.Duration
+	logLevel       string
+	logFormat      string
+
+	// cancelTimeout releases the --timeout deadline once the command is done
+	cancelTimeout context.CancelFunc = func() {}
+
+	// logger receives all diagnostics; it writes to stderr so stdout only
+	// carries the requested output
+	logger = logging.Discard()
 )
 
 var rootCmd = &cobra.Command{
@@ -39,11 +69,73 @@ using API keys (default) or OAuth client credentials stored securely in pass.
 
 The tool supports multiple output modes and can be configured via a YAML
 configuration file or command-line flags.`,
-	RunE: runGenerate,
+	PersistentPreRunE: setupCommand,
+	RunE:              runGenerate,
+
+	// Errors are printed, with secrets masked, by main
+	SilenceErrors: true,
 }
 
+// Execute runs the root command. SIGINT and SIGTERM cancel the command
+// context; a second signal terminates the process immediately.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		// Restore default signal handling so a second Ctrl-C exits at once
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded) && timeout > 0:
+		return fmt.Errorf("timed out after %s: %w", timeout, err)
+	case errors.Is(err, context.Canceled) && ctx.Err() != nil:
+		return fmt.Errorf("interrupted: %w", err)
+	}
+	return err
+}
+
+// setupCommand configures logging and bounds the whole command by
+// --timeout, if set
+func setupCommand(cmd *cobra.Command, args []string) error {
+	if err := setupLogger(cmd); err != nil {
+		return err
+	}
+
+	if timeout < 0 {
+		return fmt.Errorf("--timeout must not be negative")
+	}
+
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		cancelTimeout = cancel
+		cmd.SetContext(ctx)
+	}
+
+	return nil
+}
+
+// setupLogger creates the diagnostic logger from --log-level and
+// --log-format. --verbose is shorthand for --log-level debug.
+func setupLogger(cmd *cobra.Command) error {
+	level, err := logging.ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+
+	if verbose && !cmd.Flags().Changed("log-level") {
+		level = slog.LevelDebug
+	}
+
+	logger, err = logging.New(os.Stderr, level, logFormat)
+	return err
 }
 
 func init() {
@@ -51,12 +143,29 @@ func init() {
 
 	// Persistent flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: ~/.config/jankey/config.yaml)")
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "show API interactions and debug info")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "show API interactions and debug info (same as --log-level debug)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "log format: text or json")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "configuration profile to use (env: JANKEY_PROFILE, default: current_profile from config)")
+	rootCmd.PersistentFlags().StringVar(&apiURL, "api-url", "", "Tailscale API base URL (default: https://api.tailscale.com, env: TS_API_URL)")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "maximum duration of the whole operation, e.g. 30s (0 for no limit)")
+	rootCmd.PersistentFlags().IntVar(&maxRetries, "max-retries", retry.DefaultMaxRetries, "maximum number of retries for failed API requests")
+	rootCmd.PersistentFlags().DurationVar(&retryBudget, "retry-budget", retry.DefaultBudget, "maximum total time to wait between API request retries")
+	rootCmd.PersistentFlags().StringVar(&tailnet, "tailnet", "", "tailnet name (default: tailnet of the credential, env: TS_TAILNET)")
 
 	// Command flags
 	rootCmd.Flags().BoolVar(&initConfig, "init", false, "run interactive configuration wizard")
 	rootCmd.Flags().BoolVar(&useOAuth, "use-oauth", false, "use OAuth authentication instead of API key")
-	rootCmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON with metadata")
+	rootCmd.Flags().StringVar(&preset, "preset", "", "use a named preset from the config's presets section (flags still override)")
+	rootCmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON with metadata (same as --format json)")
+	rootCmd.Flags().StringVar(&outputFormat, "format", "plain", "output format: "+strings.Join(output.Formats(), ", "))
+	rootCmd.Flags().StringVar(&outputTemplate, "template", "", "Go text/template for --format template, e.g. '{{.ID}} {{.Key}}'")
+	rootCmd.Flags().StringVar(&outputEnvVar, "env-var", output.DefaultEnvVar, "variable name for the shell and dotenv formats")
+	rootCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "write the output to a file (atomically, mode 0600 if new) instead of stdout")
+	rootCmd.Flags().StringVar(&outputOwner, "output-owner", "", "owner of --output-file as USER[:GROUP] (default: keep the existing owner)")
+	rootCmd.Flags().BoolVar(&updateEnv, "update-env", false, "set only the --env-var line of the .env file at --output-file, keeping other entries")
+	rootCmd.Flags().StringVar(&storePass, "store-pass", "", "also store the auth key with its id and expiry in pass at PATH (default: output.store_pass from config)")
+	rootCmd.Flags().StringVar(&storeKeyring, "store-keyring", "", "also store the auth key in the Secret Service keyring as NAME (default: output.store_keyring from config)")
 	rootCmd.Flags().BoolVarP(&ephemeral, "ephemeral", "e", false, "make key ephemeral (device auto-removed when offline)")
 	rootCmd.Flags().BoolVarP(&reusable, "reusable", "r", false, "make key reusable (can authenticate multiple devices)")
 	rootCmd.Flags().BoolP("preauthorized", "p", true, "pre-authorize device (skip approval if enabled)")
@@ -73,168 +182,103 @@ func initializeConfig() {
 func runGenerate(cmd *cobra.Command, args []string) error {
 	// If --init flag is set, run interactive wizard
 	if initConfig {
-		return runInitWizard()
+		return runInitWizard(cmd.Context())
 	}
 
-	// Get config path
-	configPath := cfgFile
-	if configPath == "" {
-		var err error
-		configPath, err = config.GetConfigPath()
-		if err != nil {
-			return fmt.Errorf("failed to get config path: %w", err)
-		}
+	// Validate the output settings before creating a key
+	target, err := newOutputTarget(cmd)
+	if err != nil {
+		return err
 	}
 
 	// Load configuration
-	cfg, err := config.LoadOrDefault(configPath)
+	cfg, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
-	// Initialize pass client
-	var passClient *pass.Client
-	if pass.IsInstalled() {
-		passClient, err = pass.New()
-		if err != nil && verbose {
-			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-		}
+	// Build auth key options
+	opts, err := buildAuthKeyOptions(cmd, cfg)
+	if err != nil {
+		return err
 	}
 
-	var authKeyResp *models.AuthKeyResponse
+	passClient := newPassClient(cfg)
 
-	// Choose authentication method
-	if useOAuth {
-		// Use OAuth authentication
-		authKeyResp, err = generateWithOAuth(cmd, cfg, passClient)
-		if err != nil {
+	// Check where to store the key before creating it
+	storePath := firstNonEmpty(storePass, cfg.Output.StorePass)
+	if storePath != "" {
+		if err := pass.ValidatePath(storePath); err != nil {
 			return err
 		}
-	} else {
-		// Default: Use API key authentication
-		authKeyResp, err = generateWithAPIKey(cmd, cfg, passClient)
-		if err != nil {
-			return err
+		if passClient == nil {
+			return fmt.Errorf("cannot store the auth key at '%s': pass is not available", storePath)
 		}
 	}
 
-	// Output result
-	return outputAuthKey(authKeyResp)
-}
-
-func generateWithAPIKey(cmd *cobra.Command, cfg *models.Config, passClient *pass.Client) (*models.AuthKeyResponse, error) {
-	// Get API key
-	apiKeyValue, err := pass.GetFromPassOrEnv(passClient, cfg.APIKey.PassPathAPIKey, "TS_API_KEY")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get API key: %w\n\nRun with --init to configure credentials or set TS_API_KEY environment variable", err)
-	}
-
-	// Create API key client
-	apiClient := apikey.New(apiKeyValue, verbose)
-
-	// Validate API key
-	if err := apiClient.ValidateAPIKey(); err != nil {
-		return nil, fmt.Errorf("API key validation failed: %w", err)
+	var keyringClient *keyring.Client
+	keyringName := firstNonEmpty(storeKeyring, cfg.Output.StoreKeyring)
+	if keyringName != "" {
+		if keyringClient, err = keyring.New(); err != nil {
+			return fmt.Errorf("cannot store the auth key in the keyring as '%s': %w", keyringName, err)
+		}
+		defer keyringClient.Close()
 	}
 
-	// Build auth key options
-	opts := buildAPIKeyOptions(cmd, cfg)
-
-	// Generate auth key
-	authKeyResp, err := apiClient.CreateAuthKey(opts)
+	// Create API client for the selected authentication method
+	client, err := newAPIClient(cmd.Context(), cfg, passClient)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create auth key: %w", err)
+		return err
 	}
 
-	return authKeyResp, nil
-}
-
-func generateWithOAuth(cmd *cobra.Command, cfg *models.Config, passClient *pass.Client) (*models.AuthKeyResponse, error) {
-	// Get OAuth credentials
-	clientID, err := pass.GetFromPassOrEnv(passClient, cfg.OAuth.PassPathClientID, "TS_OAUTH_CLIENT_ID")
+	// Generate auth key
+	authKeyResp, err := client.CreateAuthKey(cmd.Context(), opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get OAuth client ID: %w\n\nRun with --init to configure credentials", err)
+		return fmt.Errorf("failed to create auth key: %w", err)
 	}
 
-	clientSecret, err := pass.GetFromPassOrEnv(passClient, cfg.OAuth.PassPathClientSecret, "TS_OAUTH_CLIENT_SECRET")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get OAuth client secret: %w\n\nRun with --init to configure credentials", err)
+	// Output result
+	if err := target.write(authKeyResp); err != nil {
+		return err
 	}
 
-	// Get OAuth access token
-	oauthClient := oauth.New(clientID, clientSecret, verbose)
-	accessToken, err := oauthClient.GetAccessToken()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get OAuth access token: %w", err)
+	if storePath != "" {
+		entry := output.PassEntry(output.NewAuthKeyOutput(authKeyResp))
+		if err := passClient.Overwrite(storePath, entry); err != nil {
+			return fmt.Errorf("auth key %s created but not stored: %w", authKeyResp.ID, err)
+		}
+		logger.Info("auth key stored in pass", "path", storePath, "id", authKeyResp.ID)
 	}
 
-	// Build auth key options (OAuth requires tags)
-	opts := buildOAuthOptions(cmd, cfg)
-
-	// Create Tailscale client and generate auth key
-	tsClient := tailscale.New(accessToken, verbose)
-	authKeyResp, err := tsClient.CreateAuthKey(opts)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create auth key: %w", err)
+	if keyringName != "" {
+		label := fmt.Sprintf("Tailscale auth key %s (expires %s)", authKeyResp.ID, authKeyResp.Expires.Format(time.DateOnly))
+		if err := keyringClient.Set(cmd.Context(), keyringName, label, authKeyResp.Key); err != nil {
+			return fmt.Errorf("auth key %s created but not stored: %w", authKeyResp.ID, err)
+		}
+		logger.Info("auth key stored in the keyring", "name", keyringName, "id", authKeyResp.ID)
 	}
 
-	return authKeyResp, nil
+	return nil
 }
 
-func buildAPIKeyOptions(cmd *cobra.Command, cfg *models.Config) apikey.AuthKeyOptions {
-	opts := apikey.AuthKeyOptions{
-		Ephemeral:     cfg.AuthKeyDefaults.Ephemeral,
-		Reusable:      cfg.AuthKeyDefaults.Reusable,
-		Preauthorized: cfg.AuthKeyDefaults.Preauthorized,
-		ExpiryDays:    cfg.AuthKeyDefaults.ExpiryDays,
-		Tags:          cfg.AuthKeyDefaults.Tags,
-		Description:   "Generated by jankey",
-	}
-
-	// Override with command-line flags
-	if ephemeral {
-		opts.Ephemeral = true
-	}
-
-	if reusable {
-		opts.Reusable = true
-	}
-
-	// Handle preauthorized flag
-	if cmd.Flags().Changed("preauthorized") {
-		opts.Preauthorized = true
-	}
-	if cmd.Flags().Changed("no-preauthorized") {
-		opts.Preauthorized = false
-	}
-
-	if expiryDays > 0 {
-		opts.ExpiryDays = expiryDays
-	}
-
-	if tags != "" {
-		opts.Tags = parseTags(tags)
-	}
-
-	if description != "" {
-		opts.Description = description
+func buildAuthKeyOptions(cmd *cobra.Command, cfg *models.Config) (tailscale.AuthKeyOptions, error) {
+	// Start from the config defaults, with the selected preset applied
+	settings, err := config.ResolveKeySettings(cfg, preset)
+	if err != nil {
+		return tailscale.AuthKeyOptions{}, err
 	}
 
-	return opts
-}
-
-func buildOAuthOptions(cmd *cobra.Command, cfg *models.Config) tailscale.AuthKeyOptions {
 	opts := tailscale.AuthKeyOptions{
-		Ephemeral:     cfg.AuthKeyDefaults.Ephemeral,
-		Reusable:      cfg.AuthKeyDefaults.Reusable,
-		Preauthorized: cfg.AuthKeyDefaults.Preauthorized,
-		ExpiryDays:    cfg.AuthKeyDefaults.ExpiryDays,
-		Tags:          cfg.AuthKeyDefaults.Tags,
-		Description:   "Generated by jankey",
+		Ephemeral:     settings.Ephemeral,
+		Reusable:      settings.Reusable,
+		Preauthorized: settings.Preauthorized,
+		ExpiryDays:    settings.ExpiryDays,
+		Tags:          settings.Tags,
+		Description:   settings.Description,
 	}
 
 	// OAuth requires tags - ensure we have at least one
-	if len(opts.Tags) == 0 {
+	if oauthSelected(cfg) && len(opts.Tags) == 0 {
 		opts.Tags = []string{"tag:container"}
 	}
 
@@ -255,6 +299,9 @@ func buildOAuthOptions(cmd *cobra.Command, cfg *models.Config) tailscale.AuthKey
 		opts.Preauthorized = false
 	}
 
+	if expiryDays < 0 || expiryDays > config.MaxExpiryDays {
+		return opts, fmt.Errorf("--expiry-days must be between 1 and %d", config.MaxExpiryDays)
+	}
 	if expiryDays > 0 {
 		opts.ExpiryDays = expiryDays
 	}
@@ -267,7 +314,7 @@ func buildOAuthOptions(cmd *cobra.Command, cfg *models.Config) tailscale.AuthKey
 		opts.Description = description
 	}
 
-	return opts
+	return opts, nil
 }
 
 func parseTags(tagString string) []string {
@@ -288,46 +335,6 @@ func parseTags(tagString string) []string {
 	return result
 }
 
-func outputAuthKey(resp *models.AuthKeyResponse) error {
-	if jsonOutput {
-		output := models.AuthKeyOutput{
-			Key:     resp.Key,
-			ID:      resp.ID,
-			Created: resp.Created.Format("2006-01-02T15:04:05Z"),
-			Expires: resp.Expires.Format("2006-01-02T15:04:05Z"),
-			Capabilities: models.AuthKeyOutputCapabilities{
-				Ephemeral:     resp.Capabilities.Devices.Create.Ephemeral,
-				Reusable:      resp.Capabilities.Devices.Create.Reusable,
-				Preauthorized: resp.Capabilities.Devices.Create.Preauthorized,
-			},
-			Tags: resp.Capabilities.Devices.Create.Tags,
-		}
-
-		jsonData, err := json.MarshalIndent(output, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal JSON output: %w", err)
-		}
-
-		fmt.Println(string(jsonData))
-	} else {
-		// Simple stdout output - just the key
-		fmt.Println(resp.Key)
-
-		// Copy to clipboard on macOS
-		if runtime.GOOS == "darwin" {
-			if err := copyToClipboard(resp.Key); err != nil {
-				if verbose {
-					fmt.Fprintf(os.Stderr, "Warning: failed to copy to clipboard: %v\n", err)
-				}
-			} else if verbose {
-				fmt.Fprintf(os.Stderr, "Auth key copied to clipboard\n")
-			}
-		}
-	}
-
-	return nil
-}
-
 func copyToClipboard(text string) error {
 	cmd := exec.Command("pbcopy")
 	stdin, err := cmd.StdinPipe()
@@ -349,4 +356,3 @@ func copyToClipboard(text string) error {
 
 	return cmd.Wait()
 }
-
@@ -1,10 +1,16 @@
 module github.com/ironicbadger/jankey
 
-go 1.25.0
+go 1.26.0
+
+require (
+	github.com/godbus/dbus/v5 v5.2.2
+	github.com/spf13/cobra v1.10.1
+	golang.org/x/term v0.46.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/cobra v1.10.1 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/sys v0.48.0 // indirect
 )
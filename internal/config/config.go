@@ -4,8 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/ironicbadger/jankey/internal/credential"
 	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/ironicbadger/jankey/internal/pass"
+	"github.com/ironicbadger/jankey/internal/tailscale"
 	"gopkg.in/yaml.v3"
 )
 
@@ -34,13 +40,18 @@ func Load(configPath string) (*models.Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	file, err := parseFile(configPath, data)
+	if err != nil {
+		return nil, err
+	}
+
 	var config models.Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := file.doc.Decode(&config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
 	// Validate config
-	if err := validateConfig(&config); err != nil {
+	if err := file.validate(&config); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
@@ -55,9 +66,9 @@ func Save(config *models.Config, configPath string) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	data, err := yaml.Marshal(config)
+	data, err := Marshal(config)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
 
 	if err := os.WriteFile(configPath, data, 0600); err != nil {
@@ -67,6 +78,23 @@ func Save(config *models.Config, configPath string) error {
 	return nil
 }
 
+// Marshal encodes the config as written by Save
+func Marshal(config *models.Config) ([]byte, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return data, nil
+}
+
+// Validate checks a config, reporting every problem found
+func Validate(config *models.Config) error {
+	if err := validateConfig(config); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
 // LoadOrDefault loads config or returns defaults if not found
 func LoadOrDefault(configPath string) (*models.Config, error) {
 	config, err := Load(configPath)
@@ -83,6 +111,7 @@ func LoadOrDefault(configPath string) (*models.Config, error) {
 // GetDefaultConfig returns the default configuration
 func GetDefaultConfig() *models.Config {
 	return &models.Config{
+		Version: CurrentVersion,
 		APIKey: models.APIKeyConfig{
 			PassPathAPIKey: "tailscale/api-key",
 		},
@@ -100,28 +129,140 @@ func GetDefaultConfig() *models.Config {
 	}
 }
 
-// validateConfig checks if the config is valid
+// validateConfig checks if the config is valid, reporting every problem
+// found as a ValidationError
 func validateConfig(config *models.Config) error {
+	return newValidationError(checkConfig(config))
+}
+
+// checkConfig returns every problem with the config
+func checkConfig(config *models.Config) []*FieldError {
+	v := &validation{}
+
+	if config.Version < 0 || config.Version > CurrentVersion {
+		v.addf("version", "unsupported version %d: this release supports up to version %d", config.Version, CurrentVersion)
+	}
+
+	// With profiles, credentials may be configured per profile only
+	validateSettings(v, config, len(config.Profiles) == 0)
+	validatePresets(v, config)
+	validateProfiles(v, config)
+
+	return v.errs
+}
+
+// validateSettings checks the settings a profile can override
+func validateSettings(v *validation, config *models.Config, requireAuth bool) {
 	// At least one auth method must be configured
-	hasAPIKey := config.APIKey.PassPathAPIKey != ""
-	hasOAuth := config.OAuth.PassPathClientID != "" && config.OAuth.PassPathClientSecret != ""
+	hasAPIKey := config.APIKey.PassPathAPIKey != "" || config.APIKey.FilePathAPIKey != "" ||
+		len(config.APIKey.CommandAPIKey.Argv) > 0 || config.APIKey.KeyringAPIKey != ""
+	hasOAuth := (config.OAuth.PassPathClientID != "" || config.OAuth.FilePathClientID != "" ||
+		len(config.OAuth.CommandClientID.Argv) > 0 || config.OAuth.KeyringClientID != "") &&
+		(config.OAuth.PassPathClientSecret != "" || config.OAuth.FilePathClientSecret != "" ||
+			len(config.OAuth.CommandClientSecret.Argv) > 0 || config.OAuth.KeyringClientSecret != "")
+
+	if requireAuth && !hasAPIKey && !hasOAuth {
+		v.addf("", "at least one authentication method must be configured (API key or OAuth)")
+	}
 
-	if !hasAPIKey && !hasOAuth {
-		return fmt.Errorf("at least one authentication method must be configured (API key or OAuth)")
+	switch config.AuthMethod {
+	case "", models.AuthMethodAPIKey:
+	case models.AuthMethodOAuth:
+		if requireAuth && !hasOAuth {
+			v.addf("auth_method", "'oauth' requires the OAuth client ID and secret in oauth.pass_path_*, oauth.file_path_*, oauth.command_* or oauth.keyring_*")
+		}
+	default:
+		v.addf("auth_method", "invalid value '%s': must be '%s' or '%s'", config.AuthMethod, models.AuthMethodAPIKey, models.AuthMethodOAuth)
 	}
 
-	if config.AuthKeyDefaults.ExpiryDays < 1 || config.AuthKeyDefaults.ExpiryDays > 90 {
-		return fmt.Errorf("auth_key_defaults.expiry_days must be between 1 and 90")
+	validateSources(v, "api_key.sources", config.APIKey.Sources)
+	validateFilePath(v, "api_key.file_path_api_key", config.APIKey.FilePathAPIKey)
+	validateCommand(v, "api_key.command_api_key", config.APIKey.CommandAPIKey)
+	validateSources(v, "oauth.sources", config.OAuth.Sources)
+	validateFilePath(v, "oauth.file_path_client_id", config.OAuth.FilePathClientID)
+	validateFilePath(v, "oauth.file_path_client_secret", config.OAuth.FilePathClientSecret)
+	validateCommand(v, "oauth.command_client_id", config.OAuth.CommandClientID)
+	validateCommand(v, "oauth.command_client_secret", config.OAuth.CommandClientSecret)
+
+	if config.AuthKeyDefaults.ExpiryDays < 1 || config.AuthKeyDefaults.ExpiryDays > MaxExpiryDays {
+		v.addf("auth_key_defaults.expiry_days", "must be between 1 and %d", MaxExpiryDays)
 	}
 
 	// Validate tag format if tags are provided
-	for _, tag := range config.AuthKeyDefaults.Tags {
+	validateTags(v, "auth_key_defaults.tags", config.AuthKeyDefaults.Tags)
+
+	if config.API.BaseURL != "" {
+		if err := tailscale.ValidateBaseURL(config.API.BaseURL); err != nil {
+			v.add("api.base_url", err)
+		}
+	}
+
+	if strings.Contains(config.API.Tailnet, "/") {
+		v.addf("api.tailnet", "must not contain '/'")
+	}
+
+	if config.Pass.Backend != "" && !pass.IsBackend(config.Pass.Backend) {
+		v.addf("pass.backend", "invalid value '%s': must be %s or %s", config.Pass.Backend, pass.BackendAuto, strings.Join(pass.Backends(), ", "))
+	}
+
+	if config.Output.StorePass != "" {
+		if err := pass.ValidatePath(config.Output.StorePass); err != nil {
+			v.add("output.store_pass", err)
+		}
+	}
+}
+
+// validateTags checks that tags are in the 'tag:name' format
+func validateTags(v *validation, key string, tags []string) {
+	for i, tag := range tags {
 		if len(tag) < 5 || tag[:4] != "tag:" {
-			return fmt.Errorf("invalid tag format '%s': tags must start with 'tag:'", tag)
+			v.addf(joinKey(key, strconv.Itoa(i)), "invalid tag format '%s': tags must start with 'tag:'", tag)
 		}
 	}
+}
 
-	return nil
+// validateSources checks that credential sources are known and listed once
+func validateSources(v *validation, key string, sources []string) {
+	seen := make(map[string]bool)
+	for i, source := range sources {
+		itemKey := joinKey(key, strconv.Itoa(i))
+		switch {
+		case !credential.IsSource(source):
+			msg := fmt.Sprintf("unknown source '%s'", source)
+			if suggestion := closest(source, credential.Sources()); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean '%s'?)", suggestion)
+			}
+			v.addf(itemKey, "%s: must be one of %s", msg, strings.Join(credential.Sources(), ", "))
+		case seen[source]:
+			v.addf(itemKey, "duplicate source '%s'", source)
+		}
+		seen[source] = true
+	}
+}
+
+// validateFilePath checks that a secret file path is absolute, so it does
+// not depend on the directory jankey runs in
+func validateFilePath(v *validation, key, path string) {
+	if path != "" && !filepath.IsAbs(path) {
+		v.addf(key, "must be an absolute path")
+	}
+}
+
+// validateCommand checks a credential command's timeout, and that its
+// settings are not given without a program to run
+func validateCommand(v *validation, key string, cmd models.CommandConfig) {
+	if len(cmd.Argv) == 0 && (cmd.Timeout != "" || cmd.JSONField != "") {
+		v.addf(joinKey(key, "argv"), "is required with timeout or json_field")
+	}
+	if len(cmd.Argv) > 0 && cmd.Argv[0] == "" {
+		v.addf(joinKey(key, "argv.0"), "must name a program")
+	}
+	if _, err := credential.NewCommand(cmd); err != nil {
+		v.add(joinKey(key, "timeout"), err)
+	}
+	if cmd.JSONField != "" && slices.Contains(strings.Split(cmd.JSONField, "."), "") {
+		v.addf(joinKey(key, "json_field"), "invalid path '%s': must not contain empty elements", cmd.JSONField)
+	}
 }
 
 // ConfigExists checks if a config file exists at the given path
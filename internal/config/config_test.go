@@ -71,6 +71,117 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantError: false,
 		},
+		{
+			name: "valid credential sources",
+			config: &models.Config{
+				APIKey: models.APIKeyConfig{
+					PassPathAPIKey: "test/api-key",
+					Sources:        []string{"env", "pass"},
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{ExpiryDays: 7},
+			},
+			wantError: false,
+		},
+		{
+			name: "unknown credential source",
+			config: &models.Config{
+				APIKey: models.APIKeyConfig{
+					PassPathAPIKey: "test/api-key",
+					Sources:        []string{"env", "vault"},
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{ExpiryDays: 7},
+			},
+			wantError: true,
+		},
+		{
+			name: "file credential only",
+			config: &models.Config{
+				APIKey: models.APIKeyConfig{
+					FilePathAPIKey: "/run/secrets/ts_api_key",
+					Sources:        []string{"file"},
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{ExpiryDays: 7},
+			},
+			wantError: false,
+		},
+		{
+			name: "relative credential file",
+			config: &models.Config{
+				APIKey: models.APIKeyConfig{
+					FilePathAPIKey: "secrets/ts_api_key",
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{ExpiryDays: 7},
+			},
+			wantError: true,
+		},
+		{
+			name: "command credential only",
+			config: &models.Config{
+				APIKey: models.APIKeyConfig{
+					CommandAPIKey: models.CommandConfig{
+						Argv:      []string{"vault", "kv", "get", "-format=json", "secret/tailscale"},
+						Timeout:   "10s",
+						JSONField: "data.data.api_key",
+					},
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{ExpiryDays: 7},
+			},
+			wantError: false,
+		},
+		{
+			name: "keyring OAuth credentials only",
+			config: &models.Config{
+				AuthMethod: models.AuthMethodOAuth,
+				OAuth: models.OAuthConfig{
+					KeyringClientID:     "tailscale/oauth-client-id",
+					KeyringClientSecret: "tailscale/oauth-client-secret",
+					Sources:             []string{"keyring"},
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{ExpiryDays: 7},
+			},
+			wantError: false,
+		},
+		{
+			name: "invalid command timeout",
+			config: &models.Config{
+				APIKey: models.APIKeyConfig{
+					CommandAPIKey: models.CommandConfig{Argv: []string{"vault"}, Timeout: "soon"},
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{ExpiryDays: 7},
+			},
+			wantError: true,
+		},
+		{
+			name: "command settings without argv",
+			config: &models.Config{
+				APIKey: models.APIKeyConfig{
+					PassPathAPIKey: "test/api-key",
+					CommandAPIKey:  models.CommandConfig{JSONField: "key"},
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{ExpiryDays: 7},
+			},
+			wantError: true,
+		},
+		{
+			name: "unknown pass backend",
+			config: &models.Config{
+				APIKey:          models.APIKeyConfig{PassPathAPIKey: "test/api-key"},
+				Pass:            models.PassConfig{Backend: "keepass"},
+				AuthKeyDefaults: models.AuthKeyDefaults{ExpiryDays: 7},
+			},
+			wantError: true,
+		},
+		{
+			name: "duplicate credential source",
+			config: &models.Config{
+				APIKey: models.APIKeyConfig{PassPathAPIKey: "test/api-key"},
+				OAuth: models.OAuthConfig{
+					Sources: []string{"pass", "pass"},
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{ExpiryDays: 7},
+			},
+			wantError: true,
+		},
 		{
 			name: "invalid tag format",
 			config: &models.Config{
@@ -99,6 +210,36 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "invalid store_pass path",
+			config: &models.Config{
+				APIKey: models.APIKeyConfig{
+					PassPathAPIKey: "test/api-key",
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{
+					ExpiryDays: 7,
+				},
+				Output: models.OutputConfig{
+					StorePass: "../outside",
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "invalid API base URL",
+			config: &models.Config{
+				APIKey: models.APIKeyConfig{
+					PassPathAPIKey: "test/api-key",
+				},
+				AuthKeyDefaults: models.AuthKeyDefaults{
+					ExpiryDays: 7,
+				},
+				API: models.APIConfig{
+					BaseURL: "api.tailscale.com",
+				},
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
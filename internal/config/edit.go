@@ -0,0 +1,327 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a config file edited in place, preserving comments and key
+// order
+type Document struct {
+	path string
+	file *parsedFile
+}
+
+// OpenDocument reads the config file at path for editing. A missing file
+// yields an empty document.
+func OpenDocument(path string) (*Document, error) {
+	doc := &Document{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := doc.parse(data); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ParseDocument parses config file contents for editing
+func ParseDocument(path string, data []byte) (*Document, error) {
+	doc := &Document{path: path}
+	if err := doc.parse(data); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (d *Document) parse(data []byte) error {
+	file, err := parseFile(d.path, data)
+	if err != nil {
+		return err
+	}
+
+	d.file = file
+	return nil
+}
+
+// Migrations returns the migrations applied to the document when it was
+// read, upgrading it to CurrentVersion
+func (d *Document) Migrations() []Migration {
+	return d.file.migrations
+}
+
+// Path returns the file path of the document
+func (d *Document) Path() string {
+	return d.path
+}
+
+// Set sets a dotted key, such as auth_key_defaults.expiry_days, to a value
+// parsed according to the key's type. Lists are comma-separated.
+func (d *Document) Set(key, value string) error {
+	t, err := KeyType(key)
+	if err != nil {
+		return err
+	}
+
+	node, err := valueNode(t, value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	parts := strings.Split(key, ".")
+	mapping := d.file.root()
+	for i, part := range parts[:len(parts)-1] {
+		child := mappingValue(mapping, part)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			mapping.Content = append(mapping.Content, scalarNode(part), child)
+		}
+		if child.Kind != yaml.MappingNode {
+			// An empty or null section can be replaced by a mapping
+			if child.Kind != yaml.ScalarNode || child.Tag != "!!null" {
+				return fmt.Errorf("cannot set %s: %s is not a section", key, strings.Join(parts[:i+1], "."))
+			}
+			child.Kind, child.Tag, child.Value = yaml.MappingNode, "!!map", ""
+		}
+		mapping = child
+	}
+
+	last := parts[len(parts)-1]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == last {
+			old := mapping.Content[i+1]
+			node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			mapping.Content[i+1] = node
+			return nil
+		}
+	}
+
+	mapping.Content = append(mapping.Content, scalarNode(last), node)
+	return nil
+}
+
+// Unset removes a dotted key, and any sections left empty by removing it.
+// It reports whether the key was present.
+func (d *Document) Unset(key string) (bool, error) {
+	if _, err := KeyType(key); err != nil {
+		return false, err
+	}
+
+	return unsetIn(d.file.root(), strings.Split(key, ".")), nil
+}
+
+func unsetIn(mapping *yaml.Node, parts []string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != parts[0] {
+			continue
+		}
+
+		if len(parts) > 1 {
+			child := mapping.Content[i+1]
+			if child.Kind != yaml.MappingNode || !unsetIn(child, parts[1:]) {
+				return false
+			}
+			if len(child.Content) > 0 {
+				return true
+			}
+		}
+
+		mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		return true
+	}
+	return false
+}
+
+// Config decodes the document merged over the built-in defaults, as it
+// would be loaded as the only config file
+func (d *Document) Config() (*models.Config, error) {
+	var values map[string]any
+	if err := d.file.doc.Decode(&values); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", d.path, err)
+	}
+
+	layered := &Layered{Sources: make(map[string]Source), values: make(map[string]any)}
+
+	defaults, err := toMap(GetDefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	layered.merge(defaults, Source{Layer: LayerDefault})
+	layered.merge(values, Source{Layer: LayerUser, Location: d.path})
+
+	return fromMap(layered.values)
+}
+
+// Validate checks the document for unknown keys and with validateConfig,
+// reporting problems at their line and column
+func (d *Document) Validate() error {
+	cfg, err := d.Config()
+	if err != nil {
+		return err
+	}
+
+	if err := d.file.validate(cfg); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
+// Bytes encodes the document
+func (d *Document) Bytes() ([]byte, error) {
+	data, err := marshalNode(d.file.doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return data, nil
+}
+
+// Save validates the document and writes it back to its file
+func (d *Document) Save() error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if err := os.WriteFile(d.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// KeyType returns the Go type of the config value at a dotted key. Map
+// sections, such as presets and profiles, take the map key as one element,
+// e.g. presets.ci.expiry_days.
+func KeyType(key string) (reflect.Type, error) {
+	t := reflect.TypeOf(models.Config{})
+
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		if part == "" {
+			return nil, fmt.Errorf("invalid config key '%s'", key)
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := yamlField(t, part)
+			if !ok {
+				return nil, fmt.Errorf("unknown config key '%s'", strings.Join(parts[:i+1], "."))
+			}
+			t = field.Type
+		case reflect.Map:
+			if !namePattern.MatchString(part) {
+				return nil, fmt.Errorf("invalid name '%s' in config key '%s'", part, key)
+			}
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("unknown config key '%s': %s is not a section", key, strings.Join(parts[:i], "."))
+		}
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t, nil
+}
+
+// yamlField finds a struct field by its YAML key
+func yamlField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tagName, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if tagName == name && tagName != "-" && field.IsExported() {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// valueNode parses a value for a key of type t into a YAML node
+func valueNode(t reflect.Type, value string) (*yaml.Node, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle}, nil
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a boolean", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}, nil
+	case reflect.Int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not an integer", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(v)}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item, Style: yaml.DoubleQuotedStyle})
+				}
+			}
+			return seq, nil
+		}
+	}
+	return nil, fmt.Errorf("it is a section, set its keys individually")
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// marshalNode encodes a YAML document with the two-space indentation used
+// by config files
+func marshalNode(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Get returns the effective value at a dotted key
+func (l *Layered) Get(key string) (any, bool) {
+	v := lookup(l.values, key)
+	return v, v != nil
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const editYAML = `# jankey config
+api_key:
+  pass_path_api_key: "tailscale/api-key"  # from pass
+
+auth_key_defaults:
+  # a week
+  expiry_days: 7
+  tags: []
+`
+
+func TestDocumentSet(t *testing.T) {
+	doc, err := ParseDocument("config.yaml", []byte(editYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sets := [][2]string{
+		{"auth_key_defaults.expiry_days", "14"},
+		{"auth_key_defaults.tags", "tag:ci, tag:web"},
+		{"auth_key_defaults.ephemeral", "true"},
+		{"api.tailnet", "example.com"},
+		{"presets.ci.reusable", "false"},
+	}
+	for _, kv := range sets {
+		if err := doc.Set(kv[0], kv[1]); err != nil {
+			t.Fatalf("Set(%s) error = %v", kv[0], err)
+		}
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+
+	for _, want := range []string{
+		"# jankey config",
+		"# from pass",
+		"# a week\n  expiry_days: 14",
+		`tags: ["tag:ci", "tag:web"]`,
+		"ephemeral: true",
+		"api:\n  tailnet: \"example.com\"",
+		"presets:\n  ci:\n    reusable: false",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("document =\n%s\nwant it to contain %q", out, want)
+		}
+	}
+
+	// Existing keys keep their position
+	if strings.Index(out, "api_key:") > strings.Index(out, "auth_key_defaults:") {
+		t.Errorf("document =\n%s\nkey order changed", out)
+	}
+
+	cfg, err := doc.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AuthKeyDefaults.ExpiryDays != 14 || len(cfg.AuthKeyDefaults.Tags) != 2 || cfg.Presets["ci"].Reusable == nil {
+		t.Errorf("Config() = %+v", cfg)
+	}
+}
+
+func TestDocumentSetErrors(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  string
+	}{
+		{key: "auth_key_defaults.expiry", value: "1", want: "unknown config key"},
+		{key: "auth_key_defaults.expiry_days", value: "week", want: "not an integer"},
+		{key: "auth_key_defaults.ephemeral", value: "maybe", want: "not a boolean"},
+		{key: "auth_key_defaults", value: "x", want: "section"},
+		{key: "api.tailnet.name", value: "x", want: "not a section"},
+		{key: "presets.ci runner.reusable", value: "true", want: "invalid name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			doc, err := ParseDocument("config.yaml", []byte(editYAML))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := doc.Set(tt.key, tt.value); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Set() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentUnset(t *testing.T) {
+	doc, err := ParseDocument("config.yaml", []byte(editYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := doc.Set("presets.ci.reusable", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"presets.ci.reusable", "auth_key_defaults.tags"} {
+		found, err := doc.Unset(key)
+		if err != nil || !found {
+			t.Errorf("Unset(%s) = %v, %v", key, found, err)
+		}
+	}
+
+	if found, _ := doc.Unset("api.tailnet"); found {
+		t.Error("Unset() of a missing key reported found")
+	}
+
+	data, _ := doc.Bytes()
+	if strings.Contains(string(data), "presets") || strings.Contains(string(data), "tags") {
+		t.Errorf("document =\n%s\nwant presets and tags removed", data)
+	}
+}
+
+func TestDocumentSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, editYAML)
+
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := doc.Set("auth_key_defaults.expiry_days", "120"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Save(); err == nil {
+		t.Error("Save() of an invalid config error = nil, want error")
+	}
+
+	if err := doc.Set("auth_key_defaults.expiry_days", "30"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil || cfg.AuthKeyDefaults.ExpiryDays != 30 {
+		t.Errorf("Load() = %+v, %v", cfg, err)
+	}
+}
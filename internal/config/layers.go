@@ -0,0 +1,488 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SystemConfigPath is the system-wide config file
+	SystemConfigPath = "/etc/jankey/config.yaml"
+
+	// ProjectConfigFile is the project config file, found by walking up from
+	// the working directory
+	ProjectConfigFile = ".jankey.yaml"
+
+	// EnvPrefix prefixes environment variables overriding config values
+	EnvPrefix = "JANKEY_"
+)
+
+// Configuration layers, from lowest to highest precedence
+const (
+	LayerDefault = "default"
+	LayerSystem  = "system"
+	LayerUser    = "user"
+	LayerProject = "project"
+	LayerEnv     = "env"
+)
+
+// projectForbiddenKeys may not be set by a project config, so that a
+// repository cannot redirect credentials to another API server, choose the
+// password store they are read from, or make generated keys overwrite
+// secrets in pass or the keyring
+var projectForbiddenKeys = []string{"api.base_url", "api_key", "oauth", "output", "pass", "profiles"}
+
+// Source is where an effective config value came from
+type Source struct {
+	// Layer is one of the Layer constants
+	Layer string
+
+	// Location is the file path, or environment variable name
+	Location string
+}
+
+func (s Source) String() string {
+	if s.Location == "" {
+		return s.Layer
+	}
+	return s.Layer + " (" + s.Location + ")"
+}
+
+// LoadOptions configure LoadLayers. Zero values select the defaults.
+type LoadOptions struct {
+	// SystemPath defaults to SystemConfigPath
+	SystemPath string
+
+	// UserPath defaults to GetConfigPath
+	UserPath string
+
+	// WorkDir is where the project config search starts, defaults to the
+	// current directory
+	WorkDir string
+
+	// Environ defaults to os.Environ
+	Environ []string
+}
+
+// Layered is a configuration merged from several layers
+type Layered struct {
+	Config *models.Config
+
+	// Files are the config files that were found and merged, lowest
+	// precedence first
+	Files []Source
+
+	// Sources maps dotted keys of effective values to their source
+	Sources map[string]Source
+
+	values map[string]any
+
+	// files are the parsed config files by path, to locate problems
+	files map[string]*parsedFile
+}
+
+// Value is an effective config value and its source
+type Value struct {
+	Key    string
+	Value  string
+	Source Source
+}
+
+// LoadLayers loads and merges the built-in defaults, the system, user and
+// project config files and JANKEY_* environment variables, in increasing
+// order of precedence. Missing files are skipped.
+func LoadLayers(opts LoadOptions) (*Layered, error) {
+	if opts.SystemPath == "" {
+		opts.SystemPath = SystemConfigPath
+	}
+	if opts.UserPath == "" {
+		path, err := GetConfigPath()
+		if err != nil {
+			return nil, err
+		}
+		opts.UserPath = path
+	}
+	if opts.WorkDir == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		opts.WorkDir = dir
+	}
+	if opts.Environ == nil {
+		opts.Environ = os.Environ()
+	}
+
+	layered := &Layered{
+		Sources: make(map[string]Source),
+		values:  make(map[string]any),
+		files:   make(map[string]*parsedFile),
+	}
+
+	defaults, err := toMap(GetDefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	layered.merge(defaults, Source{Layer: LayerDefault})
+
+	files := []Source{
+		{Layer: LayerSystem, Location: opts.SystemPath},
+		{Layer: LayerUser, Location: opts.UserPath},
+	}
+	if path, ok := FindProjectConfig(opts.WorkDir); ok {
+		files = append(files, Source{Layer: LayerProject, Location: path})
+	}
+
+	var problems []*FieldError
+	for _, file := range files {
+		parsed, err := readLayer(file.Location)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var values map[string]any
+		if err := parsed.doc.Decode(&values); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", file.Location, err)
+		}
+		if values == nil {
+			values = make(map[string]any)
+		}
+
+		problems = append(problems, parsed.unknownKeys()...)
+		if file.Layer == LayerProject {
+			problems = append(problems, parsed.forbiddenKeys()...)
+		}
+
+		layered.Files = append(layered.Files, file)
+		layered.files[file.Location] = parsed
+		layered.merge(values, file)
+	}
+
+	if err := layered.mergeEnv(opts.Environ); err != nil {
+		return nil, err
+	}
+
+	cfg, err := fromMap(layered.values)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, err := range checkConfig(cfg) {
+		layered.locate(err)
+		problems = append(problems, err)
+	}
+	if err := newValidationError(problems); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	layered.Config = cfg
+	return layered, nil
+}
+
+// FindProjectConfig looks for ProjectConfigFile in dir and its parents
+func FindProjectConfig(dir string) (string, bool) {
+	for {
+		path := filepath.Join(dir, ProjectConfigFile)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Explain returns every effective leaf value with its source, sorted by key
+func (l *Layered) Explain() []Value {
+	keys := make([]string, 0, len(l.Sources))
+	for key := range l.Sources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]Value, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, Value{
+			Key:    key,
+			Value:  formatValue(lookup(l.values, key)),
+			Source: l.Sources[key],
+		})
+	}
+	return values
+}
+
+// readLayer reads and parses a config file
+func readLayer(path string) (*parsedFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	return parseFile(path, data)
+}
+
+// forbiddenKeys reports the keys in projectForbiddenKeys set by a project
+// config file
+func (f *parsedFile) forbiddenKeys() []*FieldError {
+	var errs []*FieldError
+	for _, key := range projectForbiddenKeys {
+		if at, ok := f.position(key); ok {
+			errs = append(errs, &FieldError{
+				Err:    fmt.Errorf("project config may not set '%s': set it in the user or system config", key),
+				Source: f.path,
+				Line:   at.Line,
+				Column: at.Column,
+			})
+		}
+	}
+	return errs
+}
+
+// merge deep-merges src over the layered values, recording src as the
+// source of every leaf it sets. Lists and scalars replace earlier values.
+func (l *Layered) merge(src map[string]any, source Source) {
+	mergeInto(l.values, src, "", func(key string) {
+		l.forget(key)
+	}, func(key string) {
+		l.Sources[key] = source
+	})
+}
+
+func mergeInto(dst, src map[string]any, prefix string, replace func(key string), set func(key string)) {
+	for k, v := range src {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		srcMap, srcIsMap := v.(map[string]any)
+		dstMap, dstIsMap := dst[k].(map[string]any)
+
+		if srcIsMap && dstIsMap {
+			mergeInto(dstMap, srcMap, key, replace, set)
+			continue
+		}
+
+		replace(key)
+		if srcIsMap {
+			// Copy so later layers don't modify this layer's map
+			copied := make(map[string]any)
+			mergeInto(copied, srcMap, key, replace, set)
+			dst[k] = copied
+			if len(srcMap) == 0 {
+				set(key)
+			}
+			continue
+		}
+
+		dst[k] = v
+		set(key)
+	}
+}
+
+// forget removes the sources of key and everything below it
+func (l *Layered) forget(key string) {
+	for k := range l.Sources {
+		if k == key || strings.HasPrefix(k, key+".") {
+			delete(l.Sources, k)
+		}
+	}
+}
+
+// mergeEnv applies JANKEY_* environment variables for every settable leaf
+// of models.Config
+func (l *Layered) mergeEnv(environ []string) error {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, EnvPrefix) {
+			env[name] = value
+		}
+	}
+
+	for _, leaf := range leafFields(reflect.TypeOf(models.Config{}), "") {
+		name := EnvName(leaf.key)
+		raw, ok := env[name]
+		if !ok {
+			continue
+		}
+
+		value, err := parseEnvValue(raw, leaf.kind)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+
+		l.merge(nest(leaf.key, value), Source{Layer: LayerEnv, Location: name})
+	}
+
+	return nil
+}
+
+// EnvName returns the environment variable overriding a dotted config key
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// EnvKeys returns the dotted config keys that can be set from the
+// environment
+func EnvKeys() []string {
+	var keys []string
+	for _, leaf := range leafFields(reflect.TypeOf(models.Config{}), "") {
+		keys = append(keys, leaf.key)
+	}
+	return keys
+}
+
+type leafField struct {
+	key  string
+	kind reflect.Type
+}
+
+// leafFields lists the scalar and string list fields of a config struct by
+// their dotted YAML keys. Maps, such as presets and profiles, are skipped.
+func leafFields(t reflect.Type, prefix string) []leafField {
+	var leaves []leafField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || name == "" || !field.IsExported() {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		// The format version describes a file, not a setting
+		if key == "version" {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			leaves = append(leaves, leafFields(field.Type, key)...)
+		case reflect.String, reflect.Bool, reflect.Int:
+			leaves = append(leaves, leafField{key: key, kind: field.Type})
+		case reflect.Slice:
+			if field.Type.Elem().Kind() == reflect.String {
+				leaves = append(leaves, leafField{key: key, kind: field.Type})
+			}
+		}
+	}
+	return leaves
+}
+
+// parseEnvValue converts an environment variable to a value of kind.
+// Lists are comma-separated.
+func parseEnvValue(raw string, kind reflect.Type) (any, error) {
+	switch kind.Kind() {
+	case reflect.Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a boolean", raw)
+		}
+		return v, nil
+	case reflect.Int:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not an integer", raw)
+		}
+		return v, nil
+	case reflect.Slice:
+		list := []any{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	}
+	return raw, nil
+}
+
+// nest builds a nested map setting a dotted key to value
+func nest(key string, value any) map[string]any {
+	parts := strings.Split(key, ".")
+	m := map[string]any{parts[len(parts)-1]: value}
+	for i := len(parts) - 2; i >= 0; i-- {
+		m = map[string]any{parts[i]: m}
+	}
+	return m
+}
+
+// lookup returns the value at a dotted key, or nil
+func lookup(values map[string]any, key string) any {
+	var current any = values
+	for _, part := range strings.Split(key, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+// formatValue formats a leaf value for display
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any:
+		return "{}"
+	}
+	return fmt.Sprint(v)
+}
+
+// toMap converts a config to a generic map
+func toMap(cfg *models.Config) (map[string]any, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return values, nil
+}
+
+// fromMap converts a generic map to a config
+func fromMap(values map[string]any) (*models.Config, error) {
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var cfg models.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "etc", "config.yaml")
+	user := filepath.Join(dir, "home", "config.yaml")
+	repo := filepath.Join(dir, "repo")
+	workDir := filepath.Join(repo, "services", "web")
+
+	writeFile(t, system, "api:\n  tailnet: corp.example.com\nauth_key_defaults:\n  expiry_days: 30\n")
+	writeFile(t, user, "api_key:\n  pass_path_api_key: me/api-key\nauth_key_defaults:\n  expiry_days: 14\n  reusable: true\n")
+	writeFile(t, filepath.Join(repo, ProjectConfigFile), "auth_key_defaults:\n  tags: [\"tag:web\"]\n")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	layered, err := LoadLayers(LoadOptions{
+		SystemPath: system,
+		UserPath:   user,
+		WorkDir:    workDir,
+		Environ:    []string{"JANKEY_AUTH_KEY_DEFAULTS_EPHEMERAL=true", "JANKEY_PROFILE=ignored", "HOME=/root"},
+	})
+	if err != nil {
+		t.Fatalf("LoadLayers() error = %v", err)
+	}
+
+	cfg := layered.Config
+	if cfg.API.Tailnet != "corp.example.com" || cfg.APIKey.PassPathAPIKey != "me/api-key" ||
+		cfg.AuthKeyDefaults.ExpiryDays != 14 || !cfg.AuthKeyDefaults.Reusable || !cfg.AuthKeyDefaults.Ephemeral ||
+		!cfg.AuthKeyDefaults.Preauthorized || len(cfg.AuthKeyDefaults.Tags) != 1 || cfg.AuthKeyDefaults.Tags[0] != "tag:web" {
+		t.Errorf("merged config = %+v", cfg)
+	}
+
+	if len(layered.Files) != 3 {
+		t.Errorf("Files = %v, want system, user and project", layered.Files)
+	}
+
+	wantSources := map[string]string{
+		"api.tailnet":                     LayerSystem,
+		"auth_key_defaults.expiry_days":   LayerUser,
+		"auth_key_defaults.tags":          LayerProject,
+		"auth_key_defaults.ephemeral":     LayerEnv,
+		"auth_key_defaults.preauthorized": LayerDefault,
+	}
+	for key, layer := range wantSources {
+		if got := layered.Sources[key].Layer; got != layer {
+			t.Errorf("source of %s = %q, want %q", key, got, layer)
+		}
+	}
+
+	for _, v := range layered.Explain() {
+		if v.Key == "auth_key_defaults.ephemeral" && v.Source.Location != "JANKEY_AUTH_KEY_DEFAULTS_EPHEMERAL" {
+			t.Errorf("Explain() source = %v, want environment variable name", v.Source)
+		}
+	}
+}
+
+func TestLoadLayersDefaults(t *testing.T) {
+	dir := t.TempDir()
+
+	layered, err := LoadLayers(LoadOptions{
+		SystemPath: filepath.Join(dir, "missing-system.yaml"),
+		UserPath:   filepath.Join(dir, "missing-user.yaml"),
+		WorkDir:    dir,
+		Environ:    []string{},
+	})
+	if err != nil {
+		t.Fatalf("LoadLayers() error = %v", err)
+	}
+
+	if len(layered.Files) != 0 || layered.Config.AuthKeyDefaults.ExpiryDays != GetDefaultConfig().AuthKeyDefaults.ExpiryDays {
+		t.Errorf("LoadLayers() = %+v, want built-in defaults", layered)
+	}
+}
+
+func TestLoadLayersErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		environ []string
+		want    string
+	}{
+		{name: "project sets base URL", project: "api:\n  base_url: https://evil.example.com\n", want: "may not set 'api.base_url'"},
+		{name: "project sets credentials", project: "api_key:\n  pass_path_api_key: other\n", want: "may not set 'api_key'"},
+		{name: "project stores keys in pass", project: "output:\n  store_pass: tailscale/api-key\n", want: "may not set 'output'"},
+		{name: "project selects pass backend", project: "pass:\n  backend: gopass\n", want: "may not set 'pass'"},
+		{name: "invalid env integer", environ: []string{"JANKEY_AUTH_KEY_DEFAULTS_EXPIRY_DAYS=week"}, want: "JANKEY_AUTH_KEY_DEFAULTS_EXPIRY_DAYS"},
+		{name: "invalid merged value", environ: []string{"JANKEY_AUTH_KEY_DEFAULTS_EXPIRY_DAYS=120"}, want: "expiry_days"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.project != "" {
+				writeFile(t, filepath.Join(dir, ProjectConfigFile), tt.project)
+			}
+
+			_, err := LoadLayers(LoadOptions{
+				SystemPath: filepath.Join(dir, "system.yaml"),
+				UserPath:   filepath.Join(dir, "user.yaml"),
+				WorkDir:    dir,
+				Environ:    append([]string{}, tt.environ...),
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadLayers() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvKeys(t *testing.T) {
+	keys := strings.Join(EnvKeys(), " ")
+	for _, want := range []string{"api.tailnet", "auth_key_defaults.tags", "output.store_pass", "current_profile"} {
+		if !strings.Contains(keys, want) {
+			t.Errorf("EnvKeys() = %s, want %s", keys, want)
+		}
+	}
+	if strings.Contains(keys, "presets") || strings.Contains(keys, "profiles") {
+		t.Errorf("EnvKeys() = %s, want maps skipped", keys)
+	}
+
+	if got := EnvName("auth_key_defaults.expiry_days"); got != "JANKEY_AUTH_KEY_DEFAULTS_EXPIRY_DAYS" {
+		t.Errorf("EnvName() = %q", got)
+	}
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config file format version written by this release.
+// Files without a version key are version 0.
+const CurrentVersion = 1
+
+// Migration upgrades a config file from one format version to the next
+type Migration struct {
+	// From is the version the migration applies to; it produces From+1
+	From int
+
+	// Description summarizes the change for `jankey config migrate`
+	Description string
+
+	apply func(root *yaml.Node) error
+}
+
+// migrations is the upgrade chain, one entry per version. A structural
+// change to models.Config bumps CurrentVersion and appends a migration
+// rewriting older files to the new structure.
+var migrations = []Migration{
+	{
+		From:        0,
+		Description: "add the version key",
+		apply:       func(root *yaml.Node) error { return nil },
+	},
+}
+
+// parsedFile is a config file parsed and upgraded to CurrentVersion
+type parsedFile struct {
+	path string
+	doc  *yaml.Node
+
+	// migrations were applied to upgrade the file
+	migrations []Migration
+}
+
+// parseFile parses a config file and migrates it to CurrentVersion
+func parseFile(path string, data []byte) (*parsedFile, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+
+	root := doc.Content[0]
+	if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+		root.Kind, root.Tag = yaml.MappingNode, "!!map"
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s:%d:%d: config file must be a mapping of keys to values", path, root.Line, root.Column)
+	}
+
+	applied, err := migrate(root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &parsedFile{path: path, doc: &doc, migrations: applied}, nil
+}
+
+// root returns the top-level mapping of the file
+func (f *parsedFile) root() *yaml.Node {
+	return f.doc.Content[0]
+}
+
+// PendingMigrations returns the migrations that would upgrade a file of
+// the given version
+func PendingMigrations(version int) []Migration {
+	var pending []Migration
+	for _, m := range migrations {
+		if m.From >= version {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// migrate upgrades a config mapping to CurrentVersion in place and returns
+// the migrations applied
+func migrate(root *yaml.Node) ([]Migration, error) {
+	version, err := documentVersion(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("config version %d is newer than the supported version %d: upgrade jankey", version, CurrentVersion)
+	}
+
+	pending := PendingMigrations(version)
+	for _, m := range pending {
+		if err := m.apply(root); err != nil {
+			return nil, fmt.Errorf("failed to migrate config from version %d: %w", m.From, err)
+		}
+		setVersion(root, m.From+1)
+	}
+
+	return pending, nil
+}
+
+// documentVersion returns the version key of a config mapping, 0 if unset
+func documentVersion(root *yaml.Node) (int, error) {
+	node := mappingValue(root, "version")
+	if node == nil {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(node.Value)
+	if err != nil || node.Kind != yaml.ScalarNode || version < 0 {
+		return 0, fmt.Errorf("line %d: version must be a non-negative integer", node.Line)
+	}
+	return version, nil
+}
+
+// setVersion sets the version key, adding it as the first key if missing
+func setVersion(root *yaml.Node, version int) {
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "version" {
+			root.Content[i+1] = value
+			return
+		}
+	}
+
+	// Keep a header comment at the top of the file
+	key := scalarNode("version")
+	if len(root.Content) > 0 {
+		key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+// unknownKeys reports every key in the file that models.Config does not
+// define, at its position
+func (f *parsedFile) unknownKeys() []*FieldError {
+	var errs []*FieldError
+	walkKeys(f.root(), reflect.TypeOf(models.Config{}), "", func(key *yaml.Node, name string, known []string) {
+		err := fmt.Errorf("unknown key '%s'", name)
+		if suggestion := closest(key.Value, known); suggestion != "" {
+			err = fmt.Errorf("%w (did you mean '%s'?)", err, suggestion)
+		}
+		errs = append(errs, &FieldError{Err: err, Source: f.path, Line: key.Line, Column: key.Column})
+	})
+	return errs
+}
+
+// walkKeys calls unknown for each mapping key in node not defined by t
+func walkKeys(node *yaml.Node, t reflect.Type, prefix string, unknown func(key *yaml.Node, name string, known []string)) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		name := key.Value
+		if prefix != "" {
+			name = prefix + "." + key.Value
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := yamlField(t, key.Value)
+			if !ok {
+				unknown(key, name, yamlKeys(t))
+				continue
+			}
+			walkKeys(value, field.Type, name, unknown)
+		case reflect.Map:
+			walkKeys(value, t.Elem(), name, unknown)
+		}
+	}
+}
+
+// yamlKeys returns the YAML keys of a struct type
+func yamlKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// closest returns the candidate nearest to s by edit distance, if it is
+// close enough to be a likely typo
+func closest(s string, candidates []string) string {
+	best, bestDistance := "", len(s)/2+1
+	for _, c := range candidates {
+		if d := editDistance(s, c); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
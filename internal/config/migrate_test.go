@@ -0,0 +1,131 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocumentMigrate(t *testing.T) {
+	doc, err := ParseDocument("config.yaml", []byte(editYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := doc.Migrations(); len(got) != 1 || got[0].From != 0 {
+		t.Errorf("Migrations() = %+v, want the version 0 migration", got)
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# jankey config\nversion: 1\n") || !strings.Contains(string(data), "# from pass") {
+		t.Errorf("document =\n%s\nwant version added and comments kept", data)
+	}
+
+	current, err := ParseDocument("config.yaml", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := current.Migrations(); len(got) != 0 {
+		t.Errorf("Migrations() of a current file = %+v, want none", got)
+	}
+}
+
+func TestLoadMigratesOldConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, editYAML)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("Version = %d, want %d", cfg.Version, CurrentVersion)
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{name: "newer version", yaml: "version: 99\n", want: "newer than the supported version 1: upgrade jankey"},
+		{name: "invalid version", yaml: "version: one\n", want: "line 1: version must be a non-negative integer"},
+		{name: "not a mapping", yaml: "- api_key\n", want: "config.yaml:1:1: config file must be a mapping"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseFile("config.yaml", []byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseFile() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "unknown keys",
+			yaml: "api_key:\n  pass_path_api_kye: x\nauth_key_default:\n  expiry_days: 7\nfrobnicate: true\n",
+			want: []string{
+				"config.yaml:2:3: unknown key 'api_key.pass_path_api_kye' (did you mean 'pass_path_api_key'?)",
+				"config.yaml:3:1: unknown key 'auth_key_default' (did you mean 'auth_key_defaults'?)",
+				"config.yaml:5:1: unknown key 'frobnicate'",
+			},
+		},
+		{
+			name: "unknown key in map section",
+			yaml: "presets:\n  ci:\n    ephemeral: true\n    reuseable: true\n",
+			want: []string{"config.yaml:4:5: unknown key 'presets.ci.reuseable' (did you mean 'reusable'?)"},
+		},
+		{
+			name: "known keys",
+			yaml: editYAML,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parseFile("config.yaml", []byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			errs := file.unknownKeys()
+			if len(errs) != len(tt.want) {
+				t.Fatalf("unknownKeys() = %v, want %d errors", errs, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := errs[i].Error(); got != want {
+					t.Errorf("unknownKeys()[%d] = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"tags", "tags", 0},
+		{"reuseable", "reusable", 1},
+		{"tailnet", "tailent", 2},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ironicbadger/jankey/internal/models"
+)
+
+// DefaultDescription is the description of generated auth keys. jankey
+// cleanup recognizes keys created by jankey by it, so it is added to preset
+// descriptions that leave it out.
+const DefaultDescription = "Generated by jankey"
+
+// MaxExpiryDays is the longest auth key expiry Tailscale allows
+const MaxExpiryDays = 90
+
+// namePattern matches valid preset and profile names
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// KeySettings are the resolved settings for a new auth key
+type KeySettings struct {
+	Ephemeral     bool
+	Reusable      bool
+	Preauthorized bool
+	ExpiryDays    int
+	Tags          []string
+	Description   string
+}
+
+// DescriptionData is available to preset description templates
+type DescriptionData struct {
+	Preset   string
+	Hostname string
+	User     string
+	Date     string
+	Time     string
+}
+
+// PresetNames returns the names of the configured presets
+func PresetNames(config *models.Config) []string {
+	names := make([]string, 0, len(config.Presets))
+	for name := range config.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveKeySettings returns auth_key_defaults with the named preset, if
+// any, applied on top
+func ResolveKeySettings(config *models.Config, presetName string) (KeySettings, error) {
+	defaults := config.AuthKeyDefaults
+	settings := KeySettings{
+		Ephemeral:     defaults.Ephemeral,
+		Reusable:      defaults.Reusable,
+		Preauthorized: defaults.Preauthorized,
+		ExpiryDays:    defaults.ExpiryDays,
+		Tags:          defaults.Tags,
+		Description:   DefaultDescription,
+	}
+
+	if presetName == "" {
+		return settings, nil
+	}
+
+	preset, ok := config.Presets[presetName]
+	if !ok {
+		if len(config.Presets) == 0 {
+			return settings, fmt.Errorf("unknown preset '%s': no presets are configured", presetName)
+		}
+		return settings, fmt.Errorf("unknown preset '%s': available presets are %s", presetName, strings.Join(PresetNames(config), ", "))
+	}
+
+	if preset.Ephemeral != nil {
+		settings.Ephemeral = *preset.Ephemeral
+	}
+	if preset.Reusable != nil {
+		settings.Reusable = *preset.Reusable
+	}
+	if preset.Preauthorized != nil {
+		settings.Preauthorized = *preset.Preauthorized
+	}
+	if preset.ExpiryDays != 0 {
+		settings.ExpiryDays = preset.ExpiryDays
+	}
+	if preset.Tags != nil {
+		settings.Tags = preset.Tags
+	}
+
+	if preset.Description != "" {
+		description, err := RenderDescription(preset.Description, NewDescriptionData(presetName, time.Now()))
+		if err != nil {
+			return settings, fmt.Errorf("preset '%s': %w", presetName, err)
+		}
+		settings.Description = markDescription(description)
+	}
+
+	return settings, nil
+}
+
+// NewDescriptionData returns template data for the current host and user
+func NewDescriptionData(presetName string, now time.Time) DescriptionData {
+	data := DescriptionData{
+		Preset: presetName,
+		Date:   now.Format("2006-01-02"),
+		Time:   now.Format(time.RFC3339),
+	}
+
+	data.Hostname, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		data.User = u.Username
+	}
+
+	return data
+}
+
+// markDescription appends DefaultDescription to a description without it
+func markDescription(description string) string {
+	if strings.Contains(description, DefaultDescription) {
+		return description
+	}
+	if description == "" {
+		return DefaultDescription
+	}
+	return description + " (" + DefaultDescription + ")"
+}
+
+// RenderDescription executes a description template
+func RenderDescription(text string, data DescriptionData) (string, error) {
+	tmpl, err := template.New("description").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid description template: %w", err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid description template: %w", err)
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+// validatePresets checks every configured preset
+func validatePresets(v *validation, config *models.Config) {
+	for _, name := range PresetNames(config) {
+		preset := config.Presets[name]
+		key := "presets." + name
+
+		if !namePattern.MatchString(name) {
+			v.addf(key, "invalid preset name '%s': use letters, digits, '-' and '_'", name)
+		}
+
+		if preset.ExpiryDays != 0 && (preset.ExpiryDays < 1 || preset.ExpiryDays > MaxExpiryDays) {
+			v.addf(key+".expiry_days", "must be between 1 and %d", MaxExpiryDays)
+		}
+
+		validateTags(v, key+".tags", preset.Tags)
+
+		if preset.Description != "" {
+			if _, err := RenderDescription(preset.Description, DescriptionData{}); err != nil {
+				v.add(key+".description", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ironicbadger/jankey/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+const presetsYAML = `
+api_key:
+  pass_path_api_key: "tailscale/api-key"
+auth_key_defaults:
+  preauthorized: true
+  expiry_days: 7
+  tags: ["tag:container"]
+presets:
+  ci:
+    ephemeral: true
+    expiry_days: 1
+    tags: ["tag:ci"]
+    description: "ci {{.Preset}}"
+  laptop:
+    preauthorized: false
+`
+
+func loadPresetsConfig(t *testing.T) *models.Config {
+	t.Helper()
+
+	var cfg models.Config
+	if err := yaml.Unmarshal([]byte(presetsYAML), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateConfig(&cfg); err != nil {
+		t.Fatalf("validateConfig() error = %v", err)
+	}
+	return &cfg
+}
+
+func TestResolveKeySettings(t *testing.T) {
+	cfg := loadPresetsConfig(t)
+
+	tests := []struct {
+		preset string
+		want   KeySettings
+	}{
+		{
+			preset: "",
+			want:   KeySettings{Preauthorized: true, ExpiryDays: 7, Tags: []string{"tag:container"}, Description: DefaultDescription},
+		},
+		{
+			preset: "ci",
+			want:   KeySettings{Ephemeral: true, Preauthorized: true, ExpiryDays: 1, Tags: []string{"tag:ci"}, Description: "ci ci (Generated by jankey)"},
+		},
+		{
+			preset: "laptop",
+			want:   KeySettings{Preauthorized: false, ExpiryDays: 7, Tags: []string{"tag:container"}, Description: DefaultDescription},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			got, err := ResolveKeySettings(cfg, tt.preset)
+			if err != nil {
+				t.Fatalf("ResolveKeySettings() error = %v", err)
+			}
+
+			if got.Ephemeral != tt.want.Ephemeral || got.Reusable != tt.want.Reusable ||
+				got.Preauthorized != tt.want.Preauthorized || got.ExpiryDays != tt.want.ExpiryDays ||
+				strings.Join(got.Tags, ",") != strings.Join(tt.want.Tags, ",") || got.Description != tt.want.Description {
+				t.Errorf("ResolveKeySettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ResolveKeySettings(cfg, "server"); err == nil || !strings.Contains(err.Error(), "ci, laptop") {
+		t.Errorf("ResolveKeySettings(unknown) error = %v, want list of presets", err)
+	}
+}
+
+func TestMarkDescription(t *testing.T) {
+	for description, want := range map[string]string{
+		"ci on web01":                "ci on web01 (Generated by jankey)",
+		"Generated by jankey for CI": "Generated by jankey for CI",
+		"":                           DefaultDescription,
+	} {
+		if got := markDescription(description); got != want {
+			t.Errorf("markDescription(%q) = %q, want %q", description, got, want)
+		}
+	}
+}
+
+func TestRenderDescription(t *testing.T) {
+	data := NewDescriptionData("ci", time.Date(2025, 10, 1, 10, 30, 0, 0, time.UTC))
+	data.Hostname = "runner1"
+
+	got, err := RenderDescription("{{.Preset}} on {{.Hostname}} {{.Date}}", data)
+	if err != nil || got != "ci on runner1 2025-10-01" {
+		t.Errorf("RenderDescription() = %q, %v", got, err)
+	}
+
+	if _, err := RenderDescription("{{.Nope}}", data); err == nil {
+		t.Error("RenderDescription() with unknown field error = nil, want error")
+	}
+}
+
+func TestValidatePresets(t *testing.T) {
+	tests := []struct {
+		name   string
+		preset models.Preset
+		key    string
+	}{
+		{name: "invalid expiry", key: "ci", preset: models.Preset{ExpiryDays: 91}},
+		{name: "invalid tag", key: "ci", preset: models.Preset{Tags: []string{"ci"}}},
+		{name: "invalid template", key: "ci", preset: models.Preset{Description: "{{.Preset"}},
+		{name: "unknown template field", key: "ci", preset: models.Preset{Description: "{{.Branch}}"}},
+		{name: "invalid name", key: "ci runner", preset: models.Preset{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := GetDefaultConfig()
+			cfg.Presets = map[string]models.Preset{tt.key: tt.preset}
+
+			if err := validateConfig(cfg); err == nil {
+				t.Error("validateConfig() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/models"
+)
+
+// ProfileEnvVar selects a profile when --profile is not given
+const ProfileEnvVar = "JANKEY_PROFILE"
+
+// ProfileNames returns the names of the configured profiles
+func ProfileNames(config *models.Config) []string {
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SelectedProfile returns the profile to use: override (from --profile),
+// then $JANKEY_PROFILE, then current_profile. An empty name means the
+// top-level settings are used as is.
+func SelectedProfile(config *models.Config, override string) string {
+	if override != "" {
+		return override
+	}
+	if name := os.Getenv(ProfileEnvVar); name != "" {
+		return name
+	}
+	return config.CurrentProfile
+}
+
+// ApplyProfile returns a copy of config with the named profile applied
+func ApplyProfile(config *models.Config, name string) (*models.Config, error) {
+	profile, ok := config.Profiles[name]
+	if !ok {
+		if len(config.Profiles) == 0 {
+			return nil, fmt.Errorf("unknown profile '%s': no profiles are configured", name)
+		}
+		return nil, fmt.Errorf("unknown profile '%s': available profiles are %s", name, strings.Join(ProfileNames(config), ", "))
+	}
+
+	resolved := *config
+	resolved.ActiveProfile = name
+
+	if profile.AuthMethod != "" {
+		resolved.AuthMethod = profile.AuthMethod
+	}
+	if profile.APIKey != nil {
+		resolved.APIKey = *profile.APIKey
+	}
+	if profile.OAuth != nil {
+		resolved.OAuth = *profile.OAuth
+	}
+	if profile.API.BaseURL != "" {
+		resolved.API.BaseURL = profile.API.BaseURL
+	}
+	if profile.API.Tailnet != "" {
+		resolved.API.Tailnet = profile.API.Tailnet
+	}
+	if profile.AuthKeyDefaults != nil {
+		resolved.AuthKeyDefaults = *profile.AuthKeyDefaults
+	}
+	if profile.Output.StorePass != "" {
+		resolved.Output.StorePass = profile.Output.StorePass
+	}
+	if profile.Output.StoreKeyring != "" {
+		resolved.Output.StoreKeyring = profile.Output.StoreKeyring
+	}
+
+	return &resolved, nil
+}
+
+// SetCurrentProfile sets current_profile in the config file at configPath,
+// preserving the rest of the file including comments
+func SetCurrentProfile(configPath, name string) error {
+	doc, err := OpenDocument(configPath)
+	if err != nil {
+		return err
+	}
+
+	if err := doc.Set("current_profile", name); err != nil {
+		return err
+	}
+
+	return doc.Save()
+}
+
+// validateProfiles checks every configured profile as applied over the
+// top-level config. Problems with inherited top-level values are reported
+// once, for the top-level key.
+func validateProfiles(v *validation, config *models.Config) {
+	if config.CurrentProfile != "" {
+		if _, ok := config.Profiles[config.CurrentProfile]; !ok {
+			v.addf("current_profile", "profile '%s' is not defined in profiles", config.CurrentProfile)
+		}
+	}
+
+	for _, name := range ProfileNames(config) {
+		key := "profiles." + name
+
+		if !namePattern.MatchString(name) {
+			v.addf(key, "invalid profile name '%s': use letters, digits, '-' and '_'", name)
+			continue
+		}
+
+		resolved, err := ApplyProfile(config, name)
+		if err != nil {
+			v.add(key, err)
+			continue
+		}
+
+		profile := &validation{}
+		validateSettings(profile, resolved, true)
+		for _, err := range profile.errs {
+			if !v.has(err.Key, err.Err) {
+				v.add(joinKey(key, err.Key), err.Err)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ironicbadger/jankey/internal/models"
+)
+
+const profilesYAML = `# team config
+auth_key_defaults:
+  preauthorized: true
+  expiry_days: 7
+profiles:
+  prod:
+    api_key:
+      pass_path_api_key: "prod/api-key"  # production
+    api:
+      tailnet: "example.com"
+    output:
+      store_keyring: "prod/authkey"
+  homelab:
+    auth_method: oauth
+    oauth:
+      pass_path_client_id: "homelab/id"
+      pass_path_client_secret: "homelab/secret"
+    api:
+      base_url: "http://localhost:8080"
+    auth_key_defaults:
+      expiry_days: 30
+      tags: ["tag:lab"]
+`
+
+func writeProfilesConfig(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(profilesYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestApplyProfile(t *testing.T) {
+	cfg, err := Load(writeProfilesConfig(t))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	prod, err := ApplyProfile(cfg, "prod")
+	if err != nil {
+		t.Fatalf("ApplyProfile(prod) error = %v", err)
+	}
+	if prod.APIKey.PassPathAPIKey != "prod/api-key" || prod.API.Tailnet != "example.com" || prod.AuthKeyDefaults.ExpiryDays != 7 {
+		t.Errorf("ApplyProfile(prod) = %+v", prod)
+	}
+	if prod.Output.StoreKeyring != "prod/authkey" {
+		t.Errorf("ApplyProfile(prod) output = %+v, want store_keyring from the profile", prod.Output)
+	}
+	if prod.ActiveProfile != "prod" {
+		t.Errorf("ActiveProfile = %q, want prod", prod.ActiveProfile)
+	}
+
+	homelab, err := ApplyProfile(cfg, "homelab")
+	if err != nil {
+		t.Fatalf("ApplyProfile(homelab) error = %v", err)
+	}
+	if homelab.AuthMethod != models.AuthMethodOAuth || homelab.API.BaseURL != "http://localhost:8080" ||
+		homelab.AuthKeyDefaults.ExpiryDays != 30 || homelab.APIKey.PassPathAPIKey != "" {
+		t.Errorf("ApplyProfile(homelab) = %+v", homelab)
+	}
+
+	// The base config is not modified
+	if cfg.AuthMethod != "" || cfg.API.Tailnet != "" {
+		t.Errorf("base config modified: %+v", cfg)
+	}
+
+	if _, err := ApplyProfile(cfg, "staging"); err == nil || !strings.Contains(err.Error(), "homelab, prod") {
+		t.Errorf("ApplyProfile(unknown) error = %v, want list of profiles", err)
+	}
+}
+
+func TestSelectedProfile(t *testing.T) {
+	cfg := &models.Config{CurrentProfile: "prod"}
+
+	t.Setenv(ProfileEnvVar, "")
+	if got := SelectedProfile(cfg, ""); got != "prod" {
+		t.Errorf("SelectedProfile() = %q, want current_profile", got)
+	}
+
+	t.Setenv(ProfileEnvVar, "homelab")
+	if got := SelectedProfile(cfg, ""); got != "homelab" {
+		t.Errorf("SelectedProfile() = %q, want %s", got, ProfileEnvVar)
+	}
+	if got := SelectedProfile(cfg, "staging"); got != "staging" {
+		t.Errorf("SelectedProfile() = %q, want flag value", got)
+	}
+}
+
+func TestSetCurrentProfile(t *testing.T) {
+	path := writeProfilesConfig(t)
+
+	for _, name := range []string{"homelab", "prod"} {
+		if err := SetCurrentProfile(path, name); err != nil {
+			t.Fatalf("SetCurrentProfile() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# team config", "# production", `current_profile: "prod"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("config = %s, want it to contain %q", data, want)
+		}
+	}
+	if strings.Count(string(data), "current_profile") != 1 {
+		t.Errorf("config = %s, want a single current_profile", data)
+	}
+
+	cfg, err := Load(path)
+	if err != nil || cfg.CurrentProfile != "prod" {
+		t.Errorf("Load() = %+v, %v", cfg, err)
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile models.Profile
+		current string
+	}{
+		{name: "unknown current profile", current: "staging"},
+		{name: "invalid auth method", profile: models.Profile{AuthMethod: "password"}},
+		{name: "oauth without credentials", profile: models.Profile{AuthMethod: models.AuthMethodOAuth, OAuth: &models.OAuthConfig{}}},
+		{name: "invalid base URL", profile: models.Profile{API: models.APIConfig{BaseURL: "localhost"}}},
+		{name: "invalid expiry", profile: models.Profile{AuthKeyDefaults: &models.AuthKeyDefaults{ExpiryDays: 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := GetDefaultConfig()
+			cfg.Profiles = map[string]models.Profile{"prod": tt.profile}
+			cfg.CurrentProfile = tt.current
+
+			if err := validateConfig(cfg); err == nil {
+				t.Error("validateConfig() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/credential"
+	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/ironicbadger/jankey/internal/pass"
+)
+
+// SchemaID identifies the config file JSON Schema
+const SchemaID = "https://github.com/ironicbadger/jankey/config.schema.json"
+
+// schemaHints add descriptions and constraints to the generated schema,
+// keyed by Go type and field name. They mirror validateConfig.
+var schemaHints = map[string]map[string]any{
+	"Config.Version": {
+		"description": "Config file format version",
+		"minimum":     0,
+		"maximum":     CurrentVersion,
+	},
+	"Config.APIKey":                    {"description": "Tailscale API key credentials"},
+	"Config.OAuth":                     {"description": "Tailscale OAuth client credentials"},
+	"Config.AuthKeyDefaults":           {"description": "Default settings for generated auth keys"},
+	"Config.API":                       {"description": "Tailscale API endpoint"},
+	"Config.Output":                    {"description": "Where generated auth keys are written"},
+	"Config.Presets":                   {"description": "Named sets of auth key settings selected with --preset"},
+	"Config.AuthMethod":                {"description": "Authentication method", "enum": []string{models.AuthMethodAPIKey, models.AuthMethodOAuth}},
+	"Config.Profiles":                  {"description": "Named sets of settings selected with --profile"},
+	"Config.CurrentProfile":            {"description": "Profile used when --profile is not given"},
+	"Profile.AuthMethod":               {"description": "Authentication method", "enum": []string{models.AuthMethodAPIKey, models.AuthMethodOAuth}},
+	"APIKeyConfig.PassPathAPIKey":      {"description": "pass path of the API key, with #field for a field of the entry"},
+	"OAuthConfig.PassPathClientID":     {"description": "pass path of the OAuth client ID, with #field for a field of the entry"},
+	"OAuthConfig.PassPathClientSecret": {"description": "pass path of the OAuth client secret, with #field for a field of the entry"},
+	"APIKeyConfig.FilePathAPIKey":      {"description": "file holding the API key, for the file source"},
+	"OAuthConfig.FilePathClientID":     {"description": "file holding the OAuth client ID, for the file source"},
+	"OAuthConfig.FilePathClientSecret": {"description": "file holding the OAuth client secret, for the file source"},
+	"APIKeyConfig.CommandAPIKey":       {"description": "command printing the API key, for the command source"},
+	"OAuthConfig.CommandClientID":      {"description": "command printing the OAuth client ID, for the command source"},
+	"OAuthConfig.CommandClientSecret":  {"description": "command printing the OAuth client secret, for the command source"},
+	"APIKeyConfig.KeyringAPIKey":       {"description": "keyring item name of the API key, for the keyring source"},
+	"OAuthConfig.KeyringClientID":      {"description": "keyring item name of the OAuth client ID, for the keyring source"},
+	"OAuthConfig.KeyringClientSecret":  {"description": "keyring item name of the OAuth client secret, for the keyring source"},
+	"CommandConfig.Argv":               {"description": "program and arguments, run without a shell"},
+	"CommandConfig.Timeout":            {"description": "how long the command may run, such as 10s, default 30s"},
+	"CommandConfig.JSONField":          {"description": "dotted path to the credential in JSON output, such as data.api_key"},
+	"APIKeyConfig.Sources":             sourcesSchema("API key"),
+	"OAuthConfig.Sources":              sourcesSchema("OAuth client ID and secret"),
+	"AuthKeyDefaults.ExpiryDays": {
+		"description": "Days until the auth key expires, at most 90",
+		"minimum":     1,
+		"maximum":     MaxExpiryDays,
+	},
+	"AuthKeyDefaults.Tags": {"description": "ACL tags applied to devices"},
+	"Preset.ExpiryDays": {
+		"description": "Days until the auth key expires, at most 90; auth_key_defaults.expiry_days if unset",
+		"minimum":     1,
+		"maximum":     MaxExpiryDays,
+	},
+	"Preset.Tags":        {"description": "ACL tags applied to devices"},
+	"Preset.Description": {"description": "Go template for the auth key description"},
+	"APIConfig.BaseURL": {
+		"description": "Tailscale API base URL",
+		"format":      "uri",
+		"pattern":     "^https?://",
+	},
+	"APIConfig.Tailnet": {
+		"description": "Tailnet name, or - for the tailnet of the credential",
+		"pattern":     "^[^/]*$",
+	},
+	"OutputConfig.StorePass":    {"description": "pass path to store generated auth keys at"},
+	"OutputConfig.StoreKeyring": {"description": "keyring item name to store generated auth keys under"},
+	"Config.Pass":               {"description": "Password store used for pass paths"},
+	"PassConfig.Backend": {
+		"description": "Password store tool, default auto for the first of pass, gopass and passage installed",
+		"enum":        append([]string{pass.BackendAuto}, pass.Backends()...),
+	},
+}
+
+// sourcesSchema describes the credential sources of a credential
+func sourcesSchema(name string) map[string]any {
+	return map[string]any{
+		"description": "Sources tried in order for the " + name + ", default pass, env, file, command then keyring",
+		"items":       map[string]any{"type": "string", "enum": credential.Sources()},
+		"uniqueItems": true,
+	}
+}
+
+// tagSchema describes an ACL tag
+var tagSchema = map[string]any{"type": "string", "pattern": "^tag:.+"}
+
+// Schema returns a JSON Schema for the config file, generated from
+// models.Config, for editor completion and validation
+func Schema() map[string]any {
+	schema := typeSchema(reflect.TypeOf(models.Config{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = SchemaID
+	schema["title"] = "jankey configuration"
+	return schema
+}
+
+// typeSchema returns the schema of a config type
+func typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]any)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" || !field.IsExported() {
+				continue
+			}
+
+			property := typeSchema(field.Type)
+			if field.Type.Kind() == reflect.Slice && field.Name == "Tags" {
+				property["items"] = tagSchema
+			}
+			for k, v := range schemaHints[t.Name()+"."+field.Name] {
+				property[k] = v
+			}
+			properties[name] = property
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		return map[string]any{
+			"type":                 "object",
+			"propertyNames":        map[string]any{"pattern": namePattern.String()},
+			"additionalProperties": typeSchema(t.Elem()),
+		}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int:
+		return map[string]any{"type": "integer"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ironicbadger/jankey/internal/models"
+)
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("json.Marshal(Schema()) error = %v", err)
+	}
+
+	// Every config key has a property, and no others are allowed
+	properties := schema["properties"].(map[string]any)
+	for _, key := range yamlKeys(reflect.TypeOf(models.Config{})) {
+		if _, ok := properties[key]; !ok {
+			t.Errorf("schema has no property %s", key)
+		}
+	}
+	if schema["additionalProperties"] != false {
+		t.Error("schema allows additional properties")
+	}
+
+	expiry := properties["auth_key_defaults"].(map[string]any)["properties"].(map[string]any)["expiry_days"].(map[string]any)
+	if expiry["type"] != "integer" || expiry["minimum"] != 1 || expiry["maximum"] != 90 {
+		t.Errorf("expiry_days schema = %v", expiry)
+	}
+
+	presets := properties["presets"].(map[string]any)
+	preset := presets["additionalProperties"].(map[string]any)["properties"].(map[string]any)
+	if preset["tags"].(map[string]any)["items"].(map[string]any)["pattern"] != "^tag:.+" {
+		t.Errorf("preset tags schema = %v", preset["tags"])
+	}
+	if presets["propertyNames"] == nil {
+		t.Error("presets schema does not restrict names")
+	}
+}
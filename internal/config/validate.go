@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a problem with the config value at a dotted key
+type FieldError struct {
+	// Key is the dotted key of the value, with list items by index, e.g.
+	// auth_key_defaults.tags.1. It is empty for problems with the
+	// configuration as a whole.
+	Key string
+
+	Err error
+
+	// Source is the config file or environment variable that set the
+	// value, if known, and Line and Column its position in the file
+	Source string
+	Line   int
+	Column int
+}
+
+func (e *FieldError) Error() string {
+	msg := e.Err.Error()
+	if e.Key != "" {
+		msg = e.Key + ": " + msg
+	}
+
+	switch {
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.Source, e.Line, e.Column, msg)
+	case e.Source != "":
+		return e.Source + ": " + msg
+	}
+	return msg
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+// newValidationError returns a ValidationError for errs, or nil if there
+// are none
+func newValidationError(errs []*FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d problems:", len(e.Errors))
+	for _, err := range e.Errors {
+		b.WriteString("\n  " + err.Error())
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// validation collects the problems found validating a config
+type validation struct {
+	errs []*FieldError
+}
+
+func (v *validation) add(key string, err error) {
+	v.errs = append(v.errs, &FieldError{Key: key, Err: err})
+}
+
+func (v *validation) addf(key, format string, args ...any) {
+	v.add(key, fmt.Errorf(format, args...))
+}
+
+// has reports whether an equal problem was already found
+func (v *validation) has(key string, err error) bool {
+	for _, e := range v.errs {
+		if e.Key == key && e.Err.Error() == err.Error() {
+			return true
+		}
+	}
+	return false
+}
+
+// joinKey joins dotted key elements, skipping empty ones
+func joinKey(parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, ".")
+}
+
+// validate checks cfg, decoded from the file, and reports unknown keys and
+// invalid values at their position in the file
+func (f *parsedFile) validate(cfg *models.Config) error {
+	errs := f.unknownKeys()
+	for _, err := range checkConfig(cfg) {
+		f.locate(err)
+		errs = append(errs, err)
+	}
+	return newValidationError(errs)
+}
+
+// locate sets the source of err to the file, at the position of its key or
+// of the closest enclosing section present in the file
+func (f *parsedFile) locate(err *FieldError) {
+	err.Source = f.path
+	if at, _ := f.position(err.Key); at != nil {
+		err.Line, err.Column = at.Line, at.Column
+	}
+}
+
+// position returns the node marking the position of a dotted key, or of the
+// closest enclosing section present, and whether the key itself is present
+func (f *parsedFile) position(key string) (*yaml.Node, bool) {
+	if key == "" {
+		return nil, false
+	}
+
+	var at *yaml.Node
+	node := f.root()
+	for _, part := range strings.Split(key, ".") {
+		value, pos := childNode(node, part)
+		if value == nil {
+			return at, false
+		}
+		node, at = value, pos
+	}
+	return at, true
+}
+
+// childNode returns the value of a mapping key or sequence index and the
+// node marking its position
+func childNode(node *yaml.Node, name string) (value, at *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == name {
+				return node.Content[i+1], node.Content[i]
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(node.Content) {
+			return node.Content[i], node.Content[i]
+		}
+	}
+	return nil, nil
+}
+
+// locate sets the source of err to the layer that set its key: a file
+// position, or an environment variable name
+func (l *Layered) locate(err *FieldError) {
+	source, ok := l.sourceOf(err.Key)
+	if !ok {
+		return
+	}
+
+	switch source.Layer {
+	case LayerEnv:
+		err.Source = source.Location
+	case LayerSystem, LayerUser, LayerProject:
+		if f, ok := l.files[source.Location]; ok {
+			f.locate(err)
+		}
+	}
+}
+
+// sourceOf returns the source of the value at key, the closest enclosing
+// value, or for a section the first value within it
+func (l *Layered) sourceOf(key string) (Source, bool) {
+	if key == "" {
+		return Source{}, false
+	}
+
+	for k := key; ; {
+		if source, ok := l.Sources[k]; ok {
+			return source, true
+		}
+
+		i := strings.LastIndex(k, ".")
+		if i < 0 {
+			break
+		}
+		k = k[:i]
+	}
+
+	var within []string
+	for k := range l.Sources {
+		if strings.HasPrefix(k, key+".") {
+			within = append(within, k)
+		}
+	}
+	if len(within) == 0 {
+		return Source{}, false
+	}
+	sort.Strings(within)
+	return l.Sources[within[0]], true
+}
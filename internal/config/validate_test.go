@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ironicbadger/jankey/internal/models"
+)
+
+const invalidYAML = `api_key:
+  pass_path_api_key: "tailscale/api-key"
+auth_key_defaults:
+  expiry_days: 120
+  tags: ["tag:web", "web"]
+  ephemral: true
+api:
+  tailnet: "example.com/x"
+presets:
+  ci:
+    expiry_days: 1
+    description: "{{.Nope"
+`
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, invalidYAML)
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("Load() error = nil, want error")
+	}
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+
+	want := []string{
+		path + ":6:3: unknown key 'auth_key_defaults.ephemral' (did you mean 'ephemeral'?)",
+		path + ":4:3: auth_key_defaults.expiry_days: must be between 1 and 90",
+		path + ":5:21: auth_key_defaults.tags.1: invalid tag format 'web'",
+		path + ":8:3: api.tailnet: must not contain '/'",
+		path + ":12:5: presets.ci.description: invalid description template",
+	}
+	if len(verr.Errors) != len(want) {
+		t.Fatalf("Load() error = %v, want %d problems", err, len(want))
+	}
+	for i, w := range want {
+		if got := verr.Errors[i].Error(); !strings.HasPrefix(got, w) {
+			t.Errorf("problem %d = %q, want prefix %q", i, got, w)
+		}
+	}
+	if !strings.Contains(err.Error(), "5 problems:") {
+		t.Errorf("Load() error = %v, want a count of problems", err)
+	}
+}
+
+func TestLoadLayersLocatesErrors(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "user.yaml")
+	writeFile(t, user, "api_key:\n  pass_path_api_key: me/api-key\nauth_key_defaults:\n  tags:\n    - tag:ok\n    - bad\n")
+	writeFile(t, filepath.Join(dir, ProjectConfigFile), "api:\n  base_url: https://evil.example.com\n")
+
+	_, err := LoadLayers(LoadOptions{
+		SystemPath: filepath.Join(dir, "system.yaml"),
+		UserPath:   user,
+		WorkDir:    dir,
+		Environ:    []string{"JANKEY_AUTH_KEY_DEFAULTS_EXPIRY_DAYS=0"},
+	})
+	if err == nil {
+		t.Fatal("LoadLayers() error = nil, want error")
+	}
+
+	for _, want := range []string{
+		filepath.Join(dir, ProjectConfigFile) + ":2:3: project config may not set 'api.base_url'",
+		"JANKEY_AUTH_KEY_DEFAULTS_EXPIRY_DAYS: auth_key_defaults.expiry_days: must be between 1 and 90",
+		user + ":6:7: auth_key_defaults.tags.1: invalid tag format 'bad'",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadLayers() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestValidateProfilesReportsInheritedOnce(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.AuthKeyDefaults.ExpiryDays = 0
+	cfg.Profiles = map[string]models.Profile{
+		"prod": {AuthMethod: "password"},
+	}
+
+	err := validateConfig(cfg)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("validateConfig() error = %v, want a ValidationError", err)
+	}
+
+	var keys []string
+	for _, e := range verr.Errors {
+		keys = append(keys, e.Key)
+	}
+	if got := strings.Join(keys, " "); got != "auth_key_defaults.expiry_days profiles.prod.auth_method" {
+		t.Errorf("problem keys = %s", got)
+	}
+}
+
+func TestDocumentValidateLocatesErrors(t *testing.T) {
+	doc, err := ParseDocument("config.yaml", []byte(editYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := doc.Set("api.tailnet", "a/b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A value set in memory has no line, so the problem is reported at its
+	// closest enclosing section in the file, or for the file as a whole
+	err = doc.Validate()
+	if err == nil || !strings.Contains(err.Error(), "config.yaml: api.tailnet: must not contain '/'") {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
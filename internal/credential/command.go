@@ -0,0 +1,144 @@
+package credential
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ironicbadger/jankey/internal/models"
+)
+
+// DefaultCommandTimeout limits commands without a configured timeout
+const DefaultCommandTimeout = 30 * time.Second
+
+// commandWaitDelay bounds the wait for the output of a timed out command to
+// close, which a background process it started may keep open
+const commandWaitDelay = 2 * time.Second
+
+// Command reads a credential from the output of an external command, such
+// as a vault CLI or a script. The command is run without a shell.
+type Command struct {
+	// Argv is the program and its arguments
+	Argv []string
+
+	// Timeout limits how long the command may run, DefaultCommandTimeout
+	// if zero
+	Timeout time.Duration
+
+	// JSONField is a dotted path to a string in the command's JSON output,
+	// such as "data.api_key", or empty to use the whole output
+	JSONField string
+}
+
+// NewCommand returns the command configured in cfg
+func NewCommand(cfg models.CommandConfig) (*Command, error) {
+	c := &Command{Argv: cfg.Argv, JSONField: cfg.JSONField}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid command timeout '%s': must be a positive duration like '10s'", cfg.Timeout)
+		}
+		c.Timeout = timeout
+	}
+	return c, nil
+}
+
+// Source returns SourceCommand
+func (c *Command) Source() string {
+	return SourceCommand
+}
+
+// String describes the command
+func (c *Command) String() string {
+	if len(c.Argv) == 0 {
+		return "command (not configured)"
+	}
+	return fmt.Sprintf("command '%s'", strings.Join(c.Argv, " "))
+}
+
+// Get runs the command and returns its output, without surrounding
+// whitespace, or the JSON field of it. No command being configured counts
+// as the credential not being found; a command that fails, times out or
+// prints nothing is an error including its stderr.
+func (c *Command) Get(ctx context.Context) (string, error) {
+	if len(c.Argv) == 0 {
+		return "", ErrNotFound
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.Argv[0], c.Argv[1:]...)
+	cmd.WaitDelay = commandWaitDelay
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("command timed out after %s", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("command failed: %w", err)
+	}
+
+	value := strings.TrimSpace(stdout.String())
+	if c.JSONField != "" {
+		var err error
+		if value, err = jsonField([]byte(value), c.JSONField); err != nil {
+			return "", err
+		}
+	}
+
+	if value == "" {
+		return "", errors.New("command printed no secret")
+	}
+	return value, nil
+}
+
+// jsonField returns the string at a dotted path in a JSON document.
+// Numeric path elements index arrays.
+func jsonField(data []byte, path string) (string, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "", fmt.Errorf("command output is not JSON: %w", err)
+	}
+
+	for _, elem := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			child, ok := node[elem]
+			if !ok {
+				return "", fmt.Errorf("command output has no field '%s'", path)
+			}
+			v = child
+		case []any:
+			i, err := strconv.Atoi(elem)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", fmt.Errorf("command output has no field '%s'", path)
+			}
+			v = node[i]
+		default:
+			return "", fmt.Errorf("command output has no field '%s'", path)
+		}
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("command output field '%s' is not a string", path)
+	}
+	return strings.TrimSpace(s), nil
+}
@@ -0,0 +1,107 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ironicbadger/jankey/internal/models"
+)
+
+func TestCommandGet(t *testing.T) {
+	tests := []struct {
+		name    string
+		command Command
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "output",
+			command: Command{Argv: []string{"echo", "tskey-api-cmd"}},
+			want:    "tskey-api-cmd",
+		},
+		{
+			name:    "no shell",
+			command: Command{Argv: []string{"echo", "$HOME", "*"}},
+			want:    "$HOME *",
+		},
+		{
+			name:    "json field",
+			command: Command{Argv: []string{"echo", `{"data": {"keys": [{"value": "tskey-api-json"}]}}`}, JSONField: "data.keys.0.value"},
+			want:    "tskey-api-json",
+		},
+		{
+			name:    "missing json field",
+			command: Command{Argv: []string{"echo", `{"data": {}}`}, JSONField: "data.key"},
+			wantErr: "command output has no field 'data.key'",
+		},
+		{
+			name:    "json field not a string",
+			command: Command{Argv: []string{"echo", `{"key": 1}`}, JSONField: "key"},
+			wantErr: "is not a string",
+		},
+		{
+			name:    "not json",
+			command: Command{Argv: []string{"echo", "tskey-api-cmd"}, JSONField: "key"},
+			wantErr: "command output is not JSON",
+		},
+		{
+			name:    "stderr",
+			command: Command{Argv: []string{"sh", "-c", "echo 'vault is sealed' >&2; exit 2"}},
+			wantErr: "command failed: exit status 2: vault is sealed",
+		},
+		{
+			name:    "empty output",
+			command: Command{Argv: []string{"true"}},
+			wantErr: "command printed no secret",
+		},
+		{
+			name:    "timeout",
+			command: Command{Argv: []string{"sleep", "5"}, Timeout: 50 * time.Millisecond},
+			wantErr: "command timed out after 50ms",
+		},
+		{
+			name:    "timeout with background process holding stdout",
+			command: Command{Argv: []string{"sh", "-c", "sleep 60 & echo x"}, Timeout: 50 * time.Millisecond},
+			wantErr: "command timed out after 50ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			got, err := tt.command.Get(context.Background())
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("Get() took %s, want the timeout to bound it", elapsed)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || errors.Is(err, ErrNotFound) {
+					t.Fatalf("Get() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Get() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := (&Command{}).Get(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v without a command, want ErrNotFound", err)
+	}
+}
+
+func TestNewCommand(t *testing.T) {
+	c, err := NewCommand(models.CommandConfig{Argv: []string{"vault"}, Timeout: "5s"})
+	if err != nil || c.Timeout != 5*time.Second {
+		t.Errorf("NewCommand() = %+v, %v", c, err)
+	}
+
+	for _, timeout := range []string{"5", "-1s", "0s"} {
+		if _, err := NewCommand(models.CommandConfig{Argv: []string{"vault"}, Timeout: timeout}); err == nil {
+			t.Errorf("NewCommand() with timeout %q error = nil", timeout)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package credential resolves secrets such as the Tailscale API key from an
+// ordered chain of providers, such as pass, environment variables, files,
+// external commands and the desktop keyring, and
+// reports which provider supplied them.
+package credential
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/keyring"
+	"github.com/ironicbadger/jankey/internal/logging"
+	"github.com/ironicbadger/jankey/internal/models"
+	"github.com/ironicbadger/jankey/internal/pass"
+)
+
+// Source names, as used in the sources config keys
+const (
+	SourcePass    = "pass"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceCommand = "command"
+	SourceKeyring = "keyring"
+)
+
+// DefaultSources are tried when a credential has no sources configured
+var DefaultSources = []string{SourcePass, SourceEnv, SourceFile, SourceCommand, SourceKeyring}
+
+// ErrNotFound is returned for a credential that a provider, or every
+// provider of a chain, does not have
+var ErrNotFound = errors.New("not found")
+
+// ErrUnavailable is returned by a provider whose backend, such as pass or
+// the keyring, is not available although its source was configured
+var ErrUnavailable = errors.New("backend is unavailable")
+
+// Provider supplies a credential from one source
+type Provider interface {
+	// Source is the source name, such as SourcePass
+	Source() string
+
+	// String describes where the provider looks, for messages
+	String() string
+
+	// Get returns the credential, an error wrapping ErrNotFound if the
+	// provider does not have it, or another error if its backend failed
+	Get(ctx context.Context) (string, error)
+}
+
+// Checker is implemented by providers that can find problems with how a
+// credential is stored, such as a secret file readable by all users
+type Checker interface {
+	Check() error
+}
+
+// BackendError is a provider that failed to look up a credential, as
+// opposed to one that does not have it
+type BackendError struct {
+	Provider Provider
+	Err      error
+}
+
+// Error describes the provider and its failure
+func (e *BackendError) Error() string {
+	return e.Provider.String() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the provider's error
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+// Sources returns the names of all sources
+func Sources() []string {
+	return []string{SourcePass, SourceEnv, SourceFile, SourceCommand, SourceKeyring}
+}
+
+// IsSource reports whether name is a source name
+func IsSource(name string) bool {
+	for _, s := range Sources() {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Spec describes where each source looks for a credential
+type Spec struct {
+	// Name of the credential for messages, e.g. "API key"
+	Name string
+
+	// PassPath is the pass entry, for SourcePass
+	PassPath string
+
+	// EnvVar is the environment variable, for SourceEnv. The variable with
+	// FileEnvSuffix names a file to read instead.
+	EnvVar string
+
+	// FilePath is the file, for SourceFile
+	FilePath string
+
+	// Command is the command, for SourceCommand
+	Command models.CommandConfig
+
+	// KeyringName is the keyring item, for SourceKeyring
+	KeyringName string
+}
+
+// Options are the backends shared by the providers of chains
+type Options struct {
+	// Pass is the pass client, nil if pass is not available
+	Pass *pass.Client
+
+	// Keyring is the Secret Service client, nil if no keyring is available
+	Keyring *keyring.Client
+
+	Logger *slog.Logger
+}
+
+// Chain tries providers in order until one has the credential
+type Chain struct {
+	// Name of the credential for messages
+	Name string
+
+	Providers []Provider
+
+	// Logger receives the source of the credential and failed sources,
+	// nil discards them
+	Logger *slog.Logger
+}
+
+// NewChain returns a chain of providers for the sources, in order, or
+// DefaultSources if sources is empty
+func NewChain(spec Spec, sources []string, opts Options) (*Chain, error) {
+	explicit := len(sources) > 0
+	if !explicit {
+		sources = DefaultSources
+	}
+
+	chain := &Chain{Name: spec.Name, Logger: opts.Logger}
+	for _, source := range sources {
+		switch source {
+		case SourcePass:
+			chain.Providers = append(chain.Providers, &Pass{Client: opts.Pass, Path: spec.PassPath, Explicit: explicit})
+		case SourceEnv:
+			chain.Providers = append(chain.Providers, &Env{Var: spec.EnvVar, Logger: opts.Logger})
+		case SourceFile:
+			chain.Providers = append(chain.Providers, &File{Path: spec.FilePath, Logger: opts.Logger})
+		case SourceCommand:
+			command, err := NewCommand(spec.Command)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", spec.Name, err)
+			}
+			chain.Providers = append(chain.Providers, command)
+		case SourceKeyring:
+			chain.Providers = append(chain.Providers, &Keyring{Client: opts.Keyring, Name: spec.KeyringName, Explicit: explicit})
+		default:
+			return nil, fmt.Errorf("unknown credential source '%s': must be one of %s", source, strings.Join(Sources(), ", "))
+		}
+	}
+
+	return chain, nil
+}
+
+// Get returns the credential from the first provider that has it, and that
+// provider. Providers whose backend fails are skipped with a warning; if no
+// later provider has the credential the failures are returned as
+// BackendErrors. Otherwise the error wraps ErrNotFound.
+func (c *Chain) Get(ctx context.Context) (string, Provider, error) {
+	log := logging.OrDiscard(c.Logger)
+	var failures []error
+
+	for _, p := range c.Providers {
+		value, err := p.Get(ctx)
+		if err == nil {
+			for _, failure := range failures {
+				log.Warn("credential source failed, using a later source", "credential", c.Name, "error", failure)
+			}
+			log.Debug("credential found", "credential", c.Name, "source", p.String())
+			return value, p, nil
+		}
+
+		if errors.Is(err, ErrNotFound) {
+			log.Debug("credential not found", "credential", c.Name, "source", p.String())
+			continue
+		}
+		failures = append(failures, &BackendError{Provider: p, Err: err})
+	}
+
+	if len(failures) > 0 {
+		return "", nil, fmt.Errorf("failed to get %s: %w", c.Name, errors.Join(failures...))
+	}
+
+	where := make([]string, len(c.Providers))
+	for i, p := range c.Providers {
+		where[i] = p.String()
+	}
+	return "", nil, fmt.Errorf("%s %w in %s", c.Name, ErrNotFound, strings.Join(where, " or "))
+}
+
+// Environment variables read by SourceEnv
+const (
+	EnvAPIKey            = "TS_API_KEY"
+	EnvOAuthClientID     = "TS_OAUTH_CLIENT_ID"
+	EnvOAuthClientSecret = "TS_OAUTH_CLIENT_SECRET"
+)
+
+// APIKey returns the chain for the API key configured in cfg
+func APIKey(cfg *models.Config, opts Options) (*Chain, error) {
+	return NewChain(Spec{
+		Name:        "API key",
+		PassPath:    cfg.APIKey.PassPathAPIKey,
+		EnvVar:      EnvAPIKey,
+		FilePath:    cfg.APIKey.FilePathAPIKey,
+		Command:     cfg.APIKey.CommandAPIKey,
+		KeyringName: cfg.APIKey.KeyringAPIKey,
+	}, cfg.APIKey.Sources, opts)
+}
+
+// OAuthClient returns the chains for the OAuth client ID and secret
+// configured in cfg
+func OAuthClient(cfg *models.Config, opts Options) (id, secret *Chain, err error) {
+	id, err = NewChain(Spec{
+		Name:        "OAuth client ID",
+		PassPath:    cfg.OAuth.PassPathClientID,
+		EnvVar:      EnvOAuthClientID,
+		FilePath:    cfg.OAuth.FilePathClientID,
+		Command:     cfg.OAuth.CommandClientID,
+		KeyringName: cfg.OAuth.KeyringClientID,
+	}, cfg.OAuth.Sources, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secret, err = NewChain(Spec{
+		Name:        "OAuth client secret",
+		PassPath:    cfg.OAuth.PassPathClientSecret,
+		EnvVar:      EnvOAuthClientSecret,
+		FilePath:    cfg.OAuth.FilePathClientSecret,
+		Command:     cfg.OAuth.CommandClientSecret,
+		KeyringName: cfg.OAuth.KeyringClientSecret,
+	}, cfg.OAuth.Sources, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return id, secret, nil
+}
@@ -0,0 +1,162 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ironicbadger/jankey/internal/models"
+)
+
+// fakeProvider returns a fixed value or error
+type fakeProvider struct {
+	name  string
+	value string
+	err   error
+}
+
+func (f *fakeProvider) Source() string { return f.name }
+func (f *fakeProvider) String() string { return "fake " + f.name }
+
+func (f *fakeProvider) Get(ctx context.Context) (string, error) {
+	return f.value, f.err
+}
+
+func TestChainGet(t *testing.T) {
+	locked := errors.New("gpg: decryption failed: No secret key")
+
+	tests := []struct {
+		name        string
+		providers   []Provider
+		want        string
+		wantFrom    string
+		wantErr     string
+		wantBackend bool
+	}{
+		{
+			name:      "first found",
+			providers: []Provider{&fakeProvider{name: "a", value: "one"}, &fakeProvider{name: "b", value: "two"}},
+			want:      "one",
+			wantFrom:  "a",
+		},
+		{
+			name:      "skips not found",
+			providers: []Provider{&fakeProvider{name: "a", err: ErrNotFound}, &fakeProvider{name: "b", value: "two"}},
+			want:      "two",
+			wantFrom:  "b",
+		},
+		{
+			name:      "skips failed backend",
+			providers: []Provider{&fakeProvider{name: "a", err: locked}, &fakeProvider{name: "b", value: "two"}},
+			want:      "two",
+			wantFrom:  "b",
+		},
+		{
+			name:      "not found",
+			providers: []Provider{&fakeProvider{name: "a", err: ErrNotFound}, &fakeProvider{name: "b", err: ErrNotFound}},
+			wantErr:   "API key not found in fake a or fake b",
+		},
+		{
+			name:        "backend failed",
+			providers:   []Provider{&fakeProvider{name: "a", err: locked}, &fakeProvider{name: "b", err: ErrNotFound}},
+			wantErr:     "failed to get API key: fake a: gpg: decryption failed",
+			wantBackend: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &Chain{Name: "API key", Providers: tt.providers}
+			got, from, err := chain.Get(context.Background())
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Get() error = %v, want %q", err, tt.wantErr)
+				}
+				var backendErr *BackendError
+				if errors.As(err, &backendErr) != tt.wantBackend || errors.Is(err, ErrNotFound) == tt.wantBackend {
+					t.Errorf("Get() error = %v, want backend error %v", err, tt.wantBackend)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got != tt.want || from.Source() != tt.wantFrom {
+				t.Errorf("Get() = %q from %s, want %q from %s", got, from.Source(), tt.want, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestNewChain(t *testing.T) {
+	t.Setenv("TS_API_KEY", "tskey-api-env")
+
+	cfg := &models.Config{APIKey: models.APIKeyConfig{PassPathAPIKey: "tailscale/api-key"}}
+
+	// Without pass, the default chain falls through to the environment
+	chain, err := APIKey(cfg, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.Providers) != 5 || chain.Providers[0].Source() != SourcePass {
+		t.Fatalf("default providers = %v", chain.Providers)
+	}
+	got, from, err := chain.Get(context.Background())
+	if err != nil || got != "tskey-api-env" || from.String() != "environment variable 'TS_API_KEY'" {
+		t.Errorf("Get() = %q from %v, %v", got, from, err)
+	}
+
+	cfg.APIKey.Sources = []string{SourcePass}
+	chain, err = APIKey(cfg, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := chain.Get(context.Background()); !errors.Is(err, ErrUnavailable) || !strings.Contains(err.Error(), "pass is not installed") {
+		t.Errorf("Get() error = %v, want pass unavailable", err)
+	}
+
+	cfg.APIKey.Sources = []string{"vault"}
+	if _, err := APIKey(cfg, Options{}); err == nil {
+		t.Error("APIKey() with an unknown source error = nil")
+	}
+}
+
+func TestKeyringUnavailable(t *testing.T) {
+	k := &Keyring{Name: "tailscale/api-key"}
+	if _, err := k.Get(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v without a keyring, want ErrNotFound", err)
+	}
+	if !strings.Contains(k.String(), "no keyring is available") {
+		t.Errorf("String() = %s", k.String())
+	}
+}
+
+func TestExplicitSourceUnavailable(t *testing.T) {
+	spec := Spec{Name: "API key", PassPath: "tailscale/api-key", KeyringName: "tailscale/api-key"}
+
+	// The default sources skip backends that are not available
+	chain, err := NewChain(spec, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvAPIKey, "")
+	if _, _, err := chain.Get(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() with default sources error = %v, want ErrNotFound", err)
+	}
+
+	// Listed sources report them as failed
+	for _, source := range []string{SourcePass, SourceKeyring} {
+		chain, err := NewChain(spec, []string{source}, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = chain.Get(context.Background())
+		var backendErr *BackendError
+		if !errors.As(err, &backendErr) || !errors.Is(err, ErrUnavailable) || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get() with sources [%s] error = %v, want ErrUnavailable", source, err)
+		}
+	}
+}
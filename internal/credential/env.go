@@ -0,0 +1,67 @@
+package credential
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ironicbadger/jankey/internal/logging"
+)
+
+// FileEnvSuffix is appended to a variable's name for the variable naming a
+// file holding the credential, such as TS_API_KEY_FILE
+const FileEnvSuffix = "_FILE"
+
+// Env reads a credential from an environment variable, or from the file
+// named by the variable with FileEnvSuffix
+type Env struct {
+	Var string
+
+	// Logger receives a warning for files other users can read
+	Logger *slog.Logger
+}
+
+// Source returns SourceEnv
+func (e *Env) Source() string {
+	return SourceEnv
+}
+
+// String describes the environment variable
+func (e *Env) String() string {
+	if path := os.Getenv(e.fileVar()); path != "" {
+		return fmt.Sprintf("file '%s' from environment variable '%s'", path, e.fileVar())
+	}
+	return fmt.Sprintf("environment variable '%s'", e.Var)
+}
+
+// Get returns the variable's value, or reads the file named by its _FILE
+// variable. Unset or empty variables count as not found; setting both is
+// an error, as it is unclear which is meant.
+func (e *Env) Get(ctx context.Context) (string, error) {
+	value := os.Getenv(e.Var)
+	path := os.Getenv(e.fileVar())
+
+	switch {
+	case value != "" && path != "":
+		return "", fmt.Errorf("both %s and %s are set: unset one of them", e.Var, e.fileVar())
+	case value != "":
+		return value, nil
+	case path != "":
+		return readSecretFile(path, logging.OrDiscard(e.Logger))
+	}
+	return "", ErrNotFound
+}
+
+// Check reports whether other users can read the file named by the _FILE
+// variable
+func (e *Env) Check() error {
+	if path := os.Getenv(e.fileVar()); path != "" && os.Getenv(e.Var) == "" {
+		return checkSecretFile(path)
+	}
+	return nil
+}
+
+func (e *Env) fileVar() string {
+	return e.Var + FileEnvSuffix
+}
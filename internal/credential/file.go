@@ -0,0 +1,91 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/logging"
+)
+
+// File reads a credential from a file, such as a Docker or Kubernetes
+// secret mounted into a container
+type File struct {
+	Path string
+
+	// Logger receives a warning for files other users can read
+	Logger *slog.Logger
+}
+
+// Source returns SourceFile
+func (f *File) Source() string {
+	return SourceFile
+}
+
+// String describes the file
+func (f *File) String() string {
+	if f.Path == "" {
+		return "file (not configured)"
+	}
+	return fmt.Sprintf("file '%s'", f.Path)
+}
+
+// Get reads the file. No path being configured, or the file not existing,
+// counts as the credential not being found.
+func (f *File) Get(ctx context.Context) (string, error) {
+	if f.Path == "" {
+		return "", ErrNotFound
+	}
+
+	value, err := readSecretFile(f.Path, logging.OrDiscard(f.Logger))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	return value, err
+}
+
+// Check reports whether other users can read the file
+func (f *File) Check() error {
+	if f.Path == "" {
+		return nil
+	}
+	return checkSecretFile(f.Path)
+}
+
+// readSecretFile reads a secret from a file, without surrounding
+// whitespace such as the trailing newline most tools write. A warning is
+// logged if other users can read the file.
+func readSecretFile(path string, log *slog.Logger) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+
+	if err := checkSecretFile(path); err != nil {
+		log.Warn("insecure secret file", "error", err)
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("secret file '%s' is empty", path)
+	}
+	return value, nil
+}
+
+// checkSecretFile returns an error if a secret file is readable by all
+// users
+func checkSecretFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if mode := info.Mode().Perm(); mode&0o004 != 0 {
+		return fmt.Errorf("secret file '%s' is readable by all users (mode %04o): run 'chmod 600 %s'", path, mode, path)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSecret(t *testing.T, data string, mode os.FileMode) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(data), mode); err != nil {
+		t.Fatal(err)
+	}
+	// Set the mode explicitly, as WriteFile applies the umask
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileGet(t *testing.T) {
+	ctx := context.Background()
+
+	path := writeSecret(t, "tskey-api-file\n", 0o600)
+	if got, err := (&File{Path: path}).Get(ctx); err != nil || got != "tskey-api-file" {
+		t.Errorf("Get() = %q, %v, want the trimmed file contents", got, err)
+	}
+	if err := (&File{Path: path}).Check(); err != nil {
+		t.Errorf("Check() error = %v for a private file", err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := (&File{Path: missing}).Get(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v for a missing file, want ErrNotFound", err)
+	}
+	if _, err := (&File{}).Get(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v without a path, want ErrNotFound", err)
+	}
+
+	empty := writeSecret(t, "\n", 0o600)
+	if _, err := (&File{Path: empty}).Get(ctx); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v for an empty file, want a backend error", err)
+	}
+
+	public := writeSecret(t, "tskey-api-file", 0o644)
+	if got, err := (&File{Path: public}).Get(ctx); err != nil || got != "tskey-api-file" {
+		t.Errorf("Get() = %q, %v for a world-readable file", got, err)
+	}
+	if err := (&File{Path: public}).Check(); err == nil || !strings.Contains(err.Error(), "readable by all users (mode 0644)") {
+		t.Errorf("Check() error = %v, want a world-readable warning", err)
+	}
+}
+
+func TestEnvGet(t *testing.T) {
+	ctx := context.Background()
+	env := &Env{Var: "JANKEY_TEST_SECRET"}
+	path := writeSecret(t, "from-file\n", 0o644)
+
+	t.Setenv("JANKEY_TEST_SECRET", "")
+	t.Setenv("JANKEY_TEST_SECRET_FILE", "")
+	if _, err := env.Get(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+
+	t.Setenv("JANKEY_TEST_SECRET", "from-env")
+	if got, err := env.Get(ctx); err != nil || got != "from-env" {
+		t.Errorf("Get() = %q, %v, want from-env", got, err)
+	}
+
+	t.Setenv("JANKEY_TEST_SECRET_FILE", path)
+	if _, err := env.Get(ctx); err == nil || !strings.Contains(err.Error(), "both JANKEY_TEST_SECRET and JANKEY_TEST_SECRET_FILE") {
+		t.Errorf("Get() error = %v, want both set", err)
+	}
+
+	t.Setenv("JANKEY_TEST_SECRET", "")
+	if got, err := env.Get(ctx); err != nil || got != "from-file" {
+		t.Errorf("Get() = %q, %v, want from-file", got, err)
+	}
+	if !strings.Contains(env.String(), "JANKEY_TEST_SECRET_FILE") {
+		t.Errorf("String() = %s, want the _FILE variable", env.String())
+	}
+	if err := env.Check(); err == nil {
+		t.Error("Check() error = nil for a world-readable file")
+	}
+}
@@ -0,0 +1,59 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ironicbadger/jankey/internal/keyring"
+)
+
+// Keyring reads a credential from the Secret Service keyring, such as GNOME
+// Keyring or KWallet
+type Keyring struct {
+	// Client is nil if no keyring is available
+	Client *keyring.Client
+	Name   string
+
+	// Explicit is set when the keyring was listed in the configured
+	// sources, so no keyring being available is a failure rather than the
+	// credential not being found
+	Explicit bool
+}
+
+// Source returns SourceKeyring
+func (k *Keyring) Source() string {
+	return SourceKeyring
+}
+
+// String describes the keyring item
+func (k *Keyring) String() string {
+	if k.Name == "" {
+		return "keyring (not configured)"
+	}
+	if k.Client == nil {
+		return fmt.Sprintf("keyring item '%s' (no keyring is available)", k.Name)
+	}
+	return fmt.Sprintf("keyring item '%s'", k.Name)
+}
+
+// Get reads the keyring item. No name being configured counts as the
+// credential not being found, as does no keyring being available unless
+// the source is explicit.
+func (k *Keyring) Get(ctx context.Context) (string, error) {
+	if k.Name == "" {
+		return "", ErrNotFound
+	}
+	if k.Client == nil {
+		if k.Explicit {
+			return "", ErrUnavailable
+		}
+		return "", ErrNotFound
+	}
+
+	value, err := k.Client.Get(ctx, k.Name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	return value, err
+}
@@ -0,0 +1,55 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ironicbadger/jankey/internal/pass"
+)
+
+// Pass reads a credential from a pass entry
+type Pass struct {
+	// Client is nil if pass is not available
+	Client *pass.Client
+	Path   string
+
+	// Explicit is set when pass was listed in the configured sources, so
+	// pass not being installed is a failure rather than the credential not
+	// being found
+	Explicit bool
+}
+
+// Source returns SourcePass
+func (p *Pass) Source() string {
+	return SourcePass
+}
+
+// String describes the pass entry
+func (p *Pass) String() string {
+	if p.Client == nil {
+		return fmt.Sprintf("pass at '%s' (pass is not installed)", p.Path)
+	}
+	return fmt.Sprintf("pass at '%s'", p.Path)
+}
+
+// Get reads the pass entry. No path being configured counts as the
+// credential not being found, as does pass not being installed unless the
+// source is explicit, so the default chain works without pass.
+func (p *Pass) Get(ctx context.Context) (string, error) {
+	if p.Path == "" {
+		return "", ErrNotFound
+	}
+	if p.Client == nil {
+		if p.Explicit {
+			return "", ErrUnavailable
+		}
+		return "", ErrNotFound
+	}
+
+	value, err := p.Client.Get(p.Path)
+	if errors.Is(err, pass.ErrNotFound) {
+		return "", fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	return value, err
+}
@@ -0,0 +1,125 @@
+package doctor
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ironicbadger/jankey/internal/redact"
+)
+
+// Bundle is a gzipped tar archive of diagnostic files, to attach to a bug
+// report
+type Bundle struct {
+	files []bundleFile
+}
+
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// Add adds a file to the bundle, masking Tailscale keys in its contents
+func (b *Bundle) Add(name string, data []byte) {
+	b.files = append(b.files, bundleFile{name: name, data: []byte(redact.Text(string(data)))})
+}
+
+// Names returns the names of the files in the bundle, in the order added
+func (b *Bundle) Names() []string {
+	names := make([]string, len(b.files))
+	for i, f := range b.files {
+		names[i] = f.name
+	}
+	return names
+}
+
+// Bytes returns the bundle as a gzipped tar archive. The files are placed
+// in a directory named after dir.
+func (b *Bundle) Bytes(dir string, modTime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, f := range b.files {
+		hdr := &tar.Header{
+			Name:    dir + "/" + f.name,
+			Mode:    0o600,
+			Size:    int64(len(f.data)),
+			ModTime: modTime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sensitiveEnv are environment variables holding credentials
+var sensitiveEnv = map[string]bool{
+	"TS_API_KEY":             true,
+	"TS_OAUTH_CLIENT_SECRET": true,
+	"TS_AUTHKEY":             true,
+}
+
+// Environ returns the environment variables relevant to jankey, one per
+// line and sorted, with credentials masked
+func Environ(environ []string, prefixes ...string) []byte {
+	var lines []string
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !hasAnyPrefix(name, prefixes) {
+			continue
+		}
+
+		switch {
+		case isSensitiveEnv(name):
+			value = redact.Secret(value)
+		case name == "TS_OAUTH_CLIENT_ID":
+			value = redact.Partial(value)
+		}
+		lines = append(lines, name+"="+value)
+	}
+
+	sort.Strings(lines)
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// isSensitiveEnv reports whether a variable may hold a credential
+func isSensitiveEnv(name string) bool {
+	// Variables such as TS_API_KEY_FILE name a file, not a secret
+	if strings.HasSuffix(name, "_FILE") {
+		return false
+	}
+	if sensitiveEnv[name] {
+		return true
+	}
+	for _, word := range []string{"SECRET", "TOKEN", "PASSWORD", "PASSPHRASE"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
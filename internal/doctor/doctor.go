@@ -0,0 +1,126 @@
+// Package doctor collects the outcome of diagnostic checks into a report
+// and writes debug bundles for troubleshooting. Secrets are masked in
+// everything it writes.
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ironicbadger/jankey/internal/redact"
+)
+
+// Status is the outcome of a check
+type Status string
+
+// Check statuses
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+
+	// StatusSkip is a check that could not run because an earlier check
+	// failed or it does not apply
+	StatusSkip Status = "skip"
+)
+
+// symbols are shown for each status in the text report
+var symbols = map[Status]string{
+	StatusPass: "✓",
+	StatusWarn: "⚠",
+	StatusFail: "✗",
+	StatusSkip: "-",
+}
+
+// Check is the outcome of one diagnostic check
+type Check struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+
+	// Hint suggests how to fix a warning or failure
+	Hint string `json:"hint,omitempty"`
+}
+
+// Report is the outcome of all checks, in the order they ran
+type Report struct {
+	Checks []Check `json:"checks"`
+}
+
+// Add records a check, masking Tailscale keys in its message and hint
+func (r *Report) Add(name string, status Status, message, hint string) {
+	r.Checks = append(r.Checks, Check{
+		Name:    name,
+		Status:  status,
+		Message: redact.Text(message),
+		Hint:    redact.Text(hint),
+	})
+}
+
+// Pass records a passed check
+func (r *Report) Pass(name, format string, args ...any) {
+	r.Add(name, StatusPass, fmt.Sprintf(format, args...), "")
+}
+
+// Skip records a check that did not run
+func (r *Report) Skip(name, format string, args ...any) {
+	r.Add(name, StatusSkip, fmt.Sprintf(format, args...), "")
+}
+
+// Count returns the number of checks with the given status
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed reports whether any check failed
+func (r *Report) Failed() bool {
+	return r.Count(StatusFail) > 0
+}
+
+// Summary describes the number of checks by status
+func (r *Report) Summary() string {
+	return fmt.Sprintf("%d passed, %d warnings, %d failed, %d skipped",
+		r.Count(StatusPass), r.Count(StatusWarn), r.Count(StatusFail), r.Count(StatusSkip))
+}
+
+// WriteText writes the report as a table of checks followed by a summary
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range r.Checks {
+		fmt.Fprintf(tw, "%s %s\t%s\n", symbols[c.Status], c.Name, c.Message)
+		if c.Hint != "" {
+			for _, line := range strings.Split(c.Hint, "\n") {
+				fmt.Fprintf(tw, "\t  %s\n", line)
+			}
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%s\n", r.Summary())
+	return err
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		*Report
+		Passed  int  `json:"passed"`
+		Warned  int  `json:"warnings"`
+		Failed  int  `json:"failed"`
+		Skipped int  `json:"skipped"`
+		OK      bool `json:"ok"`
+	}{r, r.Count(StatusPass), r.Count(StatusWarn), r.Count(StatusFail), r.Count(StatusSkip), !r.Failed()})
+}
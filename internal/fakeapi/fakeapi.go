@@ -0,0 +1,378 @@
+// Package fakeapi implements an in-memory stand-in for the parts of the
+// Tailscale API used by jankey, for offline and end-to-end testing.
+package fakeapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ironicbadger/jankey/internal/models"
+)
+
+const (
+	// MaxExpiry is the longest auth key expiry accepted by the API
+	MaxExpiry = 90 * 24 * time.Hour
+
+	// DefaultTokenTTL is the lifetime of issued OAuth access tokens
+	DefaultTokenTTL = time.Hour
+
+	// DefaultOAuthScope is granted to OAuth clients
+	DefaultOAuthScope = "auth_keys"
+)
+
+// Config holds the credentials and limits enforced by the fake server
+type Config struct {
+	// APIKeys accepted as basic auth usernames
+	APIKeys []string
+
+	// OAuthClients maps accepted OAuth client IDs to their secrets
+	OAuthClients map[string]string
+
+	// Tailnet is accepted in URLs in addition to "-"
+	Tailnet string
+
+	// AllowedTags restricts the tags auth keys may request, if set
+	AllowedTags []string
+
+	// TokenTTL is the lifetime of issued access tokens, defaults to DefaultTokenTTL
+	TokenTTL time.Duration
+
+	// OAuthScope is the space-separated scopes granted to OAuth clients,
+	// defaults to DefaultOAuthScope
+	OAuthScope string
+
+	// TagOwners is the tagOwners section of the policy file, defaults to
+	// AllowedTags owned by autogroup:admin
+	TagOwners map[string][]string
+}
+
+// Server is an in-memory fake Tailscale API
+type Server struct {
+	cfg    Config
+	mux    *http.ServeMux
+	mu     sync.Mutex
+	keys   map[string]*authKey
+	order  []string
+	tokens map[string]time.Time
+	faults []*Fault
+	now    func() time.Time
+}
+
+type authKey struct {
+	models.AuthKeyResponse
+	Description string
+}
+
+// New creates a fake API server with the given configuration
+func New(cfg Config) *Server {
+	if cfg.TokenTTL <= 0 {
+		cfg.TokenTTL = DefaultTokenTTL
+	}
+	if cfg.OAuthScope == "" {
+		cfg.OAuthScope = DefaultOAuthScope
+	}
+	if cfg.TagOwners == nil {
+		cfg.TagOwners = make(map[string][]string)
+		for _, tag := range cfg.AllowedTags {
+			cfg.TagOwners[tag] = []string{"autogroup:admin"}
+		}
+	}
+
+	s := &Server{
+		cfg:    cfg,
+		mux:    http.NewServeMux(),
+		keys:   make(map[string]*authKey),
+		tokens: make(map[string]time.Time),
+		now:    time.Now,
+	}
+
+	s.mux.HandleFunc("POST /api/v2/oauth/token", s.handleToken)
+	s.mux.HandleFunc("GET /api/v2/tailnet/{tailnet}/keys", s.authenticated(s.handleListKeys))
+	s.mux.HandleFunc("POST /api/v2/tailnet/{tailnet}/keys", s.authenticated(s.handleCreateKey))
+	s.mux.HandleFunc("GET /api/v2/tailnet/{tailnet}/keys/{id}", s.authenticated(s.handleGetKey))
+	s.mux.HandleFunc("DELETE /api/v2/tailnet/{tailnet}/keys/{id}", s.authenticated(s.handleDeleteKey))
+	s.mux.HandleFunc("GET /api/v2/tailnet/{tailnet}/acl", s.authenticated(s.handleGetPolicy))
+	s.mux.HandleFunc("GET /_fake/faults", s.handleListFaults)
+	s.mux.HandleFunc("POST /_fake/faults", s.handleAddFaults)
+	s.mux.HandleFunc("DELETE /_fake/faults", s.handleClearFaults)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler, applying any matching fault first
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/_fake/") {
+		if f := s.takeFault(r); f != nil {
+			if f.apply(w, r) {
+				return
+			}
+		}
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleToken implements the OAuth client credentials exchange
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+
+	if grantType := r.PostForm.Get("grant_type"); grantType != "client_credentials" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type", "error_description": fmt.Sprintf("grant_type %q is not supported", grantType)})
+		return
+	}
+
+	clientID := r.PostForm.Get("client_id")
+	clientSecret := r.PostForm.Get("client_secret")
+	if secret, ok := s.cfg.OAuthClients[clientID]; !ok || clientID == "" || secret != clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client", "error_description": "client authentication failed"})
+		return
+	}
+
+	token := "fake-token-" + randomHex(16)
+
+	s.mu.Lock()
+	s.tokens[token] = s.now().Add(s.cfg.TokenTTL)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, models.OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.cfg.TokenTTL.Seconds()),
+		Scope:       s.cfg.OAuthScope,
+	})
+}
+
+type principal int
+
+const (
+	principalAPIKey principal = iota
+	principalOAuth
+)
+
+type authenticatedHandler func(w http.ResponseWriter, r *http.Request, p principal)
+
+// authenticated checks the tailnet and credentials before calling next
+func (s *Server) authenticated(next authenticatedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := s.authenticate(r)
+		if !ok {
+			writeMessage(w, http.StatusUnauthorized, "API token invalid")
+			return
+		}
+
+		if tailnet := r.PathValue("tailnet"); tailnet != "-" && tailnet != s.cfg.Tailnet {
+			writeMessage(w, http.StatusNotFound, fmt.Sprintf("tailnet %q not found", tailnet))
+			return
+		}
+
+		next(w, r, p)
+	}
+}
+
+func (s *Server) authenticate(r *http.Request) (principal, bool) {
+	if user, _, ok := r.BasicAuth(); ok {
+		return principalAPIKey, slices.Contains(s.cfg.APIKeys, user)
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return 0, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.tokens[token]
+	if !ok || s.now().After(expires) {
+		return 0, false
+	}
+
+	return principalOAuth, true
+}
+
+func (s *Server) handleCreateKey(w http.ResponseWriter, r *http.Request, p principal) {
+	var req models.AuthKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeMessage(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	if msg := s.validateKeyRequest(req, p); msg != "" {
+		writeMessage(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	expiry := time.Duration(req.ExpirySeconds) * time.Second
+	if expiry == 0 {
+		expiry = MaxExpiry
+	}
+
+	id := "k" + randomHex(8)
+	created := s.now().UTC().Truncate(time.Second)
+	key := &authKey{
+		AuthKeyResponse: models.AuthKeyResponse{
+			ID:           id,
+			Key:          "tskey-auth-" + id + "-" + randomHex(16),
+			Created:      created,
+			Expires:      created.Add(expiry),
+			Capabilities: req.Capabilities,
+		},
+		Description: req.Description,
+	}
+
+	s.mu.Lock()
+	s.keys[id] = key
+	s.order = append(s.order, id)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, key.AuthKeyResponse)
+}
+
+// validateKeyRequest returns an error message if the request would be
+// rejected by the real API
+func (s *Server) validateKeyRequest(req models.AuthKeyRequest, p principal) string {
+	if req.ExpirySeconds < 0 {
+		return "expirySeconds must not be negative"
+	}
+
+	if time.Duration(req.ExpirySeconds)*time.Second > MaxExpiry {
+		return fmt.Sprintf("expirySeconds must be at most %d", int64(MaxExpiry.Seconds()))
+	}
+
+	tags := req.Capabilities.Devices.Create.Tags
+	if p == principalOAuth && len(tags) == 0 {
+		return "invalid capability: tags are required for auth keys created with an OAuth access token"
+	}
+
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, "tag:") || len(tag) < 5 {
+			return fmt.Sprintf("invalid capability: tag %q must start with 'tag:'", tag)
+		}
+		if len(s.cfg.AllowedTags) > 0 && !slices.Contains(s.cfg.AllowedTags, tag) {
+			return fmt.Sprintf("invalid capability: requested tag %q is invalid or not permitted", tag)
+		}
+	}
+
+	return ""
+}
+
+func (s *Server) handleListKeys(w http.ResponseWriter, r *http.Request, p principal) {
+	s.mu.Lock()
+	keys := make([]keyInfo, 0, len(s.order))
+	for _, id := range s.order {
+		keys = append(keys, s.keys[id].info())
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+func (s *Server) handleGetKey(w http.ResponseWriter, r *http.Request, p principal) {
+	s.mu.Lock()
+	key, ok := s.keys[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "key not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, key.info())
+}
+
+func (s *Server) handleDeleteKey(w http.ResponseWriter, r *http.Request, p principal) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	_, ok := s.keys[id]
+	if ok {
+		delete(s.keys, id)
+		s.order = slices.DeleteFunc(s.order, func(k string) bool { return k == id })
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "key not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGetPolicy returns a policy file with only the tagOwners section.
+// OAuth clients need a policy_file scope.
+func (s *Server) handleGetPolicy(w http.ResponseWriter, r *http.Request, p principal) {
+	if p == principalOAuth && !s.hasScope("all", "all:read", "policy_file", "policy_file:read") {
+		writeMessage(w, http.StatusForbidden, "OAuth client lacks the policy_file:read scope")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tagOwners": s.cfg.TagOwners})
+}
+
+// hasScope reports whether OAuth clients are granted any of scopes
+func (s *Server) hasScope(scopes ...string) bool {
+	for _, granted := range strings.Fields(s.cfg.OAuthScope) {
+		if slices.Contains(scopes, granted) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyInfo is an auth key as returned by the list and get endpoints,
+// without the secret
+type keyInfo struct {
+	ID           string              `json:"id"`
+	Created      time.Time           `json:"created"`
+	Expires      time.Time           `json:"expires"`
+	Description  string              `json:"description,omitempty"`
+	Capabilities models.Capabilities `json:"capabilities"`
+}
+
+func (k *authKey) info() keyInfo {
+	return keyInfo{
+		ID:           k.ID,
+		Created:      k.Created,
+		Expires:      k.Expires,
+		Description:  k.Description,
+		Capabilities: k.Capabilities,
+	}
+}
+
+// Keys returns the auth keys currently stored, in creation order
+func (s *Server) Keys() []models.AuthKeyResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]models.AuthKeyResponse, 0, len(s.order))
+	for _, id := range s.order {
+		keys = append(keys, s.keys[id].AuthKeyResponse)
+	}
+	return keys
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"message": msg})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,205 @@
+package fakeapi
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ironicbadger/jankey/internal/oauth"
+	"github.com/ironicbadger/jankey/internal/tailscale"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+
+	fake := New(Config{
+		APIKeys:      []string{"tskey-api-test"},
+		OAuthClients: map[string]string{"client-id": "client-secret"},
+		Tailnet:      "example.com",
+		AllowedTags:  []string{"tag:ci"},
+	})
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return fake, server
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	fake, server := newTestServer(t)
+	client := tailscale.New(tailscale.APIKey("tskey-api-test"), tailscale.Options{BaseURL: server.URL})
+
+	resp, err := client.CreateAuthKey(context.Background(), tailscale.AuthKeyOptions{
+		ExpiryDays:  7,
+		Description: "Generated by jankey",
+	})
+	if err != nil {
+		t.Fatalf("CreateAuthKey() error = %v", err)
+	}
+
+	if !strings.HasPrefix(resp.Key, "tskey-auth-") {
+		t.Errorf("key = %q, want tskey-auth- prefix", resp.Key)
+	}
+
+	keys, err := client.ListAuthKeys(context.Background())
+	if err != nil {
+		t.Fatalf("ListAuthKeys() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != resp.ID || keys[0].Description != "Generated by jankey" {
+		t.Fatalf("ListAuthKeys() = %+v, want the created key", keys)
+	}
+
+	if err := client.DeleteAuthKey(context.Background(), resp.ID); err != nil {
+		t.Fatalf("DeleteAuthKey() error = %v", err)
+	}
+
+	if got := fake.Keys(); len(got) != 0 {
+		t.Errorf("keys after delete = %d, want 0", len(got))
+	}
+}
+
+func TestOAuthRequiresTags(t *testing.T) {
+	_, server := newTestServer(t)
+
+	token, err := oauth.New("client-id", "client-secret", oauth.Options{BaseURL: server.URL}).GetAccessToken(context.Background())
+	if err != nil {
+		t.Fatalf("GetAccessToken() error = %v", err)
+	}
+
+	client := tailscale.New(tailscale.BearerToken(token), tailscale.Options{BaseURL: server.URL, Tailnet: "example.com"})
+
+	tests := []struct {
+		name      string
+		tags      []string
+		wantError string
+	}{
+		{name: "no tags", wantError: "tags are required"},
+		{name: "tag not permitted", tags: []string{"tag:prod"}, wantError: "not permitted"},
+		{name: "allowed tag", tags: []string{"tag:ci"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.CreateAuthKey(context.Background(), tailscale.AuthKeyOptions{ExpiryDays: 1, Tags: tt.tags})
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("CreateAuthKey() error = %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("CreateAuthKey() error = %v, want error containing %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestTokenScopeAndPolicy(t *testing.T) {
+	_, server := newTestServer(t)
+
+	token, err := oauth.New("client-id", "client-secret", oauth.Options{BaseURL: server.URL}).Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if token.Scope != DefaultOAuthScope || !oauth.CanCreateAuthKeys(token.Scope) {
+		t.Errorf("scope = %q, want %q", token.Scope, DefaultOAuthScope)
+	}
+
+	// Reading the policy file needs a policy_file scope, unlike an API key
+	client := tailscale.New(tailscale.BearerToken(token.AccessToken), tailscale.Options{BaseURL: server.URL})
+	if _, err := client.TagOwners(context.Background()); tailscale.StatusCode(err) != 403 {
+		t.Errorf("TagOwners() without policy_file scope error = %v, want status 403", err)
+	}
+
+	keyClient := tailscale.New(tailscale.APIKey("tskey-api-test"), tailscale.Options{BaseURL: server.URL})
+	owners, err := keyClient.TagOwners(context.Background())
+	if err != nil {
+		t.Fatalf("TagOwners() error = %v", err)
+	}
+	if len(owners) != 1 || len(owners["tag:ci"]) != 1 {
+		t.Errorf("TagOwners() = %v, want the allowed tags", owners)
+	}
+
+	badClient := tailscale.New(tailscale.APIKey("tskey-api-wrong"), tailscale.Options{BaseURL: server.URL})
+	if _, err := badClient.TagOwners(context.Background()); tailscale.StatusCode(err) != 401 {
+		t.Errorf("TagOwners() with invalid key error = %v, want status 401", err)
+	}
+}
+
+func TestCredentialValidation(t *testing.T) {
+	_, server := newTestServer(t)
+
+	if _, err := oauth.New("client-id", "wrong", oauth.Options{BaseURL: server.URL}).GetAccessToken(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("GetAccessToken() with wrong secret error = %v, want 401", err)
+	}
+
+	client := tailscale.New(tailscale.APIKey("tskey-api-wrong"), tailscale.Options{BaseURL: server.URL})
+	if _, err := client.ListAuthKeys(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("ListAuthKeys() with wrong key error = %v, want 401", err)
+	}
+
+	client = tailscale.New(tailscale.APIKey("tskey-api-test"), tailscale.Options{BaseURL: server.URL, Tailnet: "other.com"})
+	if _, err := client.ListAuthKeys(context.Background()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("ListAuthKeys() on unknown tailnet error = %v, want 404", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	fake, server := newTestServer(t)
+	client := tailscale.New(tailscale.APIKey("tskey-api-test"), tailscale.Options{BaseURL: server.URL})
+
+	fault, err := ParseFault("status=403,method=GET,path=/keys")
+	if err != nil {
+		t.Fatalf("ParseFault() error = %v", err)
+	}
+	fake.AddFault(fault)
+
+	if _, err := client.ListAuthKeys(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("ListAuthKeys() with fault error = %v, want 403", err)
+	}
+
+	// The fault is consumed after one request
+	if _, err := client.ListAuthKeys(context.Background()); err != nil {
+		t.Errorf("ListAuthKeys() after fault error = %v", err)
+	}
+}
+
+func TestFaultRetryAfter(t *testing.T) {
+	// Sub-second delays are rounded up, as Retry-After is in whole seconds
+	fault := Fault{Status: 429, RetryAfter: 500 * time.Millisecond}
+	w := httptest.NewRecorder()
+	fault.apply(w, httptest.NewRequest("GET", "/api/v2/tailnet/-/keys", nil))
+
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+}
+
+func TestParseFault(t *testing.T) {
+	tests := []struct {
+		spec      string
+		want      string
+		wantError bool
+	}{
+		{spec: "status=429,retry-after=2", want: "status=429,retry-after=2s"},
+		{spec: "reset,count=3", want: "reset,count=3"},
+		{spec: "slow=500ms,method=post", want: "method=POST,delay=500ms"},
+		{spec: "count=2", wantError: true},
+		{spec: "status=42", wantError: true},
+		{spec: "bogus=1", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			f, err := ParseFault(tt.spec)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ParseFault(%q) error = %v, wantError %v", tt.spec, err, tt.wantError)
+			}
+			if err == nil && f.String() != tt.want {
+				t.Errorf("ParseFault(%q) = %q, want %q", tt.spec, f.String(), tt.want)
+			}
+		})
+	}
+}
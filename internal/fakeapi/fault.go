@@ -0,0 +1,254 @@
+package fakeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fault describes an injected failure. Faults are applied in the order they
+// were added: each request consumes the first fault that matches it.
+type Fault struct {
+	// Method restricts the fault to an HTTP method, if set
+	Method string
+
+	// Path restricts the fault to request paths containing this string, if set
+	Path string
+
+	// Status is the HTTP status code to respond with, if set
+	Status int
+
+	// RetryAfter is sent as the Retry-After header, if set, rounded up to
+	// whole seconds
+	RetryAfter time.Duration
+
+	// Delay is waited before responding
+	Delay time.Duration
+
+	// Reset closes the connection without responding
+	Reset bool
+
+	// Count is the number of requests the fault applies to, defaults to 1
+	Count int
+}
+
+// ParseFault parses a fault specification of comma-separated options, e.g.
+// "status=429,retry-after=2s,count=2" or "reset,method=POST,path=/keys".
+func ParseFault(spec string) (Fault, error) {
+	var f Fault
+
+	for _, opt := range strings.Split(spec, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(opt, "=")
+
+		var err error
+		switch name {
+		case "method":
+			f.Method = strings.ToUpper(value)
+		case "path":
+			f.Path = value
+		case "status":
+			f.Status, err = strconv.Atoi(value)
+			if err == nil && (f.Status < 100 || f.Status > 599) {
+				err = fmt.Errorf("status must be between 100 and 599")
+			}
+		case "retry-after":
+			f.RetryAfter, err = parseSeconds(value)
+		case "delay", "slow":
+			f.Delay, err = parseSeconds(value)
+		case "reset":
+			f.Reset = true
+		case "count":
+			f.Count, err = strconv.Atoi(value)
+			if err == nil && f.Count < 1 {
+				err = fmt.Errorf("count must be at least 1")
+			}
+		default:
+			return Fault{}, fmt.Errorf("invalid fault '%s': unknown option '%s'", spec, name)
+		}
+
+		if err != nil {
+			return Fault{}, fmt.Errorf("invalid fault '%s': %s: %w", spec, name, err)
+		}
+	}
+
+	if f.Status == 0 && !f.Reset && f.Delay == 0 {
+		return Fault{}, fmt.Errorf("invalid fault '%s': one of status, reset or delay is required", spec)
+	}
+
+	return f, nil
+}
+
+// String returns the fault in the specification format accepted by ParseFault
+func (f Fault) String() string {
+	var opts []string
+	if f.Method != "" {
+		opts = append(opts, "method="+f.Method)
+	}
+	if f.Path != "" {
+		opts = append(opts, "path="+f.Path)
+	}
+	if f.Status != 0 {
+		opts = append(opts, "status="+strconv.Itoa(f.Status))
+	}
+	if f.RetryAfter > 0 {
+		opts = append(opts, "retry-after="+f.RetryAfter.String())
+	}
+	if f.Delay > 0 {
+		opts = append(opts, "delay="+f.Delay.String())
+	}
+	if f.Reset {
+		opts = append(opts, "reset")
+	}
+	if f.Count > 1 {
+		opts = append(opts, "count="+strconv.Itoa(f.Count))
+	}
+	return strings.Join(opts, ",")
+}
+
+// parseSeconds parses a duration, accepting plain integers as seconds
+func parseSeconds(value string) (time.Duration, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// AddFault queues a fault to be applied to subsequent requests
+func (s *Server) AddFault(f Fault) {
+	if f.Count <= 0 {
+		f.Count = 1
+	}
+
+	s.mu.Lock()
+	s.faults = append(s.faults, &f)
+	s.mu.Unlock()
+}
+
+// ClearFaults removes all queued faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	s.faults = nil
+	s.mu.Unlock()
+}
+
+// takeFault consumes and returns the first queued fault matching the request
+func (s *Server) takeFault(r *http.Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if f.Path != "" && !strings.Contains(r.URL.Path, f.Path) {
+			continue
+		}
+
+		f.Count--
+		if f.Count <= 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+
+		applied := *f
+		return &applied
+	}
+
+	return nil
+}
+
+// apply injects the fault into the response. It reports whether the
+// request was fully handled; a delay-only fault falls through to the
+// normal handler.
+func (f *Fault) apply(w http.ResponseWriter, r *http.Request) bool {
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return true
+		}
+	}
+
+	if f.Reset {
+		resetConnection(w)
+		return true
+	}
+
+	if f.Status == 0 {
+		return false
+	}
+
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(f.RetryAfter.Seconds()))))
+	}
+	writeMessage(w, f.Status, fmt.Sprintf("injected fault: %s", http.StatusText(f.Status)))
+	return true
+}
+
+// resetConnection aborts the connection with a TCP RST
+func resetConnection(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
+
+func (s *Server) handleListFaults(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	faults := make([]string, 0, len(s.faults))
+	for _, f := range s.faults {
+		faults = append(faults, f.String())
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, faults)
+}
+
+// handleAddFaults queues faults from a JSON array of fault specifications
+func (s *Server) handleAddFaults(w http.ResponseWriter, r *http.Request) {
+	var specs []string
+	if err := json.NewDecoder(r.Body).Decode(&specs); err != nil {
+		writeMessage(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	faults := make([]Fault, 0, len(specs))
+	for _, spec := range specs {
+		f, err := ParseFault(spec)
+		if err != nil {
+			writeMessage(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		faults = append(faults, f)
+	}
+
+	for _, f := range faults {
+		s.AddFault(f)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleClearFaults(w http.ResponseWriter, r *http.Request) {
+	s.ClearFaults()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,310 @@
+// Package fakekeyring implements an in-memory stand-in for the parts of the
+// freedesktop Secret Service D-Bus API used by jankey, for testing the
+// keyring backend without GNOME Keyring or KWallet.
+package fakekeyring
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/ironicbadger/jankey/internal/keyring"
+)
+
+// CollectionPath is the only collection, also the default alias
+const CollectionPath = dbus.ObjectPath("/org/freedesktop/secrets/collection/login")
+
+// Config holds the behaviour of the fake service
+type Config struct {
+	// Locked starts the collection locked, so reading or storing a secret
+	// needs an unlock prompt
+	Locked bool
+
+	// DismissPrompts makes every prompt complete as dismissed
+	DismissPrompts bool
+}
+
+// Item is a stored secret
+type Item struct {
+	Path       dbus.ObjectPath
+	Label      string
+	Attributes map[string]string
+	Value      string
+}
+
+// Service is a fake Secret Service
+type Service struct {
+	cfg Config
+
+	mu      sync.Mutex
+	conn    *dbus.Conn
+	locked  bool
+	items   map[dbus.ObjectPath]*Item
+	prompts int
+
+	// next numbers new items and prompts
+	next int
+
+	// pending are the objects each prompt not yet shown unlocks
+	pending map[dbus.ObjectPath][]dbus.ObjectPath
+}
+
+// New returns a fake service with no items
+func New(cfg Config) *Service {
+	return &Service{
+		cfg:     cfg,
+		locked:  cfg.Locked,
+		items:   make(map[dbus.ObjectPath]*Item),
+		pending: make(map[dbus.ObjectPath][]dbus.ObjectPath),
+	}
+}
+
+// Export serves the fake on a bus connection under the Secret Service name
+func (s *Service) Export(conn *dbus.Conn) error {
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+
+	exports := []struct {
+		v     any
+		path  dbus.ObjectPath
+		iface string
+	}{
+		{&serviceObject{s}, keyring.ServicePath, keyring.ServiceIface},
+		{&collectionObject{s}, CollectionPath, keyring.CollectionIface},
+		{&itemObject{s}, CollectionPath, keyring.ItemIface},
+		{&sessionObject{}, "/org/freedesktop/secrets/session", keyring.SessionIface},
+		{&promptObject{s}, "/org/freedesktop/secrets/prompt", keyring.PromptIface},
+	}
+	for _, e := range exports {
+		var err error
+		if e.iface == keyring.ServiceIface || e.iface == keyring.CollectionIface {
+			err = conn.Export(e.v, e.path, e.iface)
+		} else {
+			// Items, sessions and prompts are objects below the path
+			err = conn.ExportSubtree(e.v, e.path, e.iface)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", e.iface, err)
+		}
+	}
+
+	reply, err := conn.RequestName(keyring.ServiceName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %w", keyring.ServiceName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("%s is already owned on the bus", keyring.ServiceName)
+	}
+	return nil
+}
+
+// Add stores an item, as if created by another application
+func (s *Service) Add(label string, attributes map[string]string, value string) *Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.add(label, attributes, value)
+}
+
+func (s *Service) add(label string, attributes map[string]string, value string) *Item {
+	s.next++
+	item := &Item{
+		Path:       dbus.ObjectPath(fmt.Sprintf("%s/%d", CollectionPath, s.next)),
+		Label:      label,
+		Attributes: maps.Clone(attributes),
+		Value:      value,
+	}
+	s.items[item.Path] = item
+	return item
+}
+
+// Items returns copies of the stored items, ordered by path
+func (s *Service) Items() []Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []Item
+	for _, path := range slices.Sorted(maps.Keys(s.items)) {
+		items = append(items, *s.items[path])
+	}
+	return items
+}
+
+// Lock locks the collection again
+func (s *Service) Lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locked = true
+}
+
+// Prompts returns how many prompts were shown
+func (s *Service) Prompts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prompts
+}
+
+// search returns the items with all of attributes
+func (s *Service) search(attributes map[string]string) []dbus.ObjectPath {
+	var paths []dbus.ObjectPath
+	for _, path := range slices.Sorted(maps.Keys(s.items)) {
+		item := s.items[path]
+		match := true
+		for k, v := range attributes {
+			if item.Attributes[k] != v {
+				match = false
+				break
+			}
+		}
+		if match {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+var errLocked = dbus.NewError("org.freedesktop.Secret.Error.IsLocked", []any{"the collection is locked"})
+
+func errNoSuchObject(path dbus.ObjectPath) *dbus.Error {
+	return dbus.NewError("org.freedesktop.Secret.Error.NoSuchObject", []any{fmt.Sprintf("no such object %s", path)})
+}
+
+// serviceObject implements org.freedesktop.Secret.Service
+type serviceObject struct{ s *Service }
+
+func (o *serviceObject) OpenSession(algorithm string, input dbus.Variant) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
+	if algorithm != "plain" {
+		return dbus.Variant{}, "", dbus.NewError("org.freedesktop.DBus.Error.NotSupported", []any{"only the plain algorithm is supported"})
+	}
+	return dbus.MakeVariant(""), "/org/freedesktop/secrets/session/1", nil
+}
+
+func (o *serviceObject) SearchItems(attributes map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, *dbus.Error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+
+	paths := o.s.search(attributes)
+	if o.s.locked {
+		return []dbus.ObjectPath{}, append([]dbus.ObjectPath{}, paths...), nil
+	}
+	return append([]dbus.ObjectPath{}, paths...), []dbus.ObjectPath{}, nil
+}
+
+func (o *serviceObject) Unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+
+	if !o.s.locked {
+		return objects, "/", nil
+	}
+
+	o.s.next++
+	prompt := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/secrets/prompt/%d", o.s.next))
+	o.s.pending[prompt] = objects
+	return []dbus.ObjectPath{}, prompt, nil
+}
+
+func (o *serviceObject) ReadAlias(name string) (dbus.ObjectPath, *dbus.Error) {
+	if name == keyring.DefaultAlias {
+		return CollectionPath, nil
+	}
+	return "/", nil
+}
+
+// collectionObject implements org.freedesktop.Secret.Collection
+type collectionObject struct{ s *Service }
+
+func (o *collectionObject) CreateItem(properties map[string]dbus.Variant, secret keyring.Secret, replace bool) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+
+	if o.s.locked {
+		return "", "", errLocked
+	}
+
+	label, _ := properties[keyring.ItemIface+".Label"].Value().(string)
+	attributes, _ := properties[keyring.ItemIface+".Attributes"].Value().(map[string]string)
+
+	if replace {
+		for _, path := range o.s.search(attributes) {
+			if maps.Equal(o.s.items[path].Attributes, attributes) {
+				item := o.s.items[path]
+				item.Label, item.Value = label, string(secret.Value)
+				return path, "/", nil
+			}
+		}
+	}
+
+	return o.s.add(label, attributes, string(secret.Value)).Path, "/", nil
+}
+
+// itemObject implements org.freedesktop.Secret.Item for every item
+type itemObject struct{ s *Service }
+
+func (o *itemObject) GetSecret(msg dbus.Message, session dbus.ObjectPath) (keyring.Secret, *dbus.Error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+
+	path, _ := msg.Headers[dbus.FieldPath].Value().(dbus.ObjectPath)
+	item, ok := o.s.items[path]
+	if !ok {
+		return keyring.Secret{}, errNoSuchObject(path)
+	}
+	if o.s.locked {
+		return keyring.Secret{}, errLocked
+	}
+	return keyring.Secret{Session: session, Parameters: []byte{}, Value: []byte(item.Value), ContentType: "text/plain"}, nil
+}
+
+func (o *itemObject) Delete(msg dbus.Message) (dbus.ObjectPath, *dbus.Error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+
+	path, _ := msg.Headers[dbus.FieldPath].Value().(dbus.ObjectPath)
+	if _, ok := o.s.items[path]; !ok {
+		return "", errNoSuchObject(path)
+	}
+	if o.s.locked {
+		return "", errLocked
+	}
+	delete(o.s.items, path)
+	return "/", nil
+}
+
+// sessionObject implements org.freedesktop.Secret.Session
+type sessionObject struct{}
+
+func (o *sessionObject) Close() *dbus.Error {
+	return nil
+}
+
+// promptObject implements org.freedesktop.Secret.Prompt, unlocking the
+// collection unless prompts are dismissed
+type promptObject struct{ s *Service }
+
+func (o *promptObject) Prompt(msg dbus.Message, windowID string) *dbus.Error {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+
+	path, _ := msg.Headers[dbus.FieldPath].Value().(dbus.ObjectPath)
+	objects, ok := o.s.pending[path]
+	if !ok {
+		return errNoSuchObject(path)
+	}
+	delete(o.s.pending, path)
+	o.s.prompts++
+
+	dismissed := o.s.cfg.DismissPrompts
+	unlocked := []dbus.ObjectPath{}
+	if !dismissed {
+		o.s.locked = false
+		unlocked = objects
+	}
+
+	// Completed follows the reply to Prompt, as with a real service
+	conn := o.s.conn
+	go conn.Emit(path, keyring.PromptIface+".Completed", dismissed, dbus.MakeVariant(unlocked))
+	return nil
+}
@@ -0,0 +1,284 @@
+// Package keyring reads and writes secrets in a desktop keyring, such as
+// GNOME Keyring or KWallet, through the freedesktop Secret Service D-Bus
+// API.
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// D-Bus names of the Secret Service API
+const (
+	ServiceName     = "org.freedesktop.secrets"
+	ServicePath     = dbus.ObjectPath("/org/freedesktop/secrets")
+	ServiceIface    = "org.freedesktop.Secret.Service"
+	CollectionIface = "org.freedesktop.Secret.Collection"
+	ItemIface       = "org.freedesktop.Secret.Item"
+	SessionIface    = "org.freedesktop.Secret.Session"
+	PromptIface     = "org.freedesktop.Secret.Prompt"
+
+	// DefaultAlias names the collection new items are stored in
+	DefaultAlias = "default"
+)
+
+// Application is the service attribute of the items jankey stores, so they
+// can be found with 'secret-tool lookup service jankey username NAME'
+const Application = "jankey"
+
+// noPrompt is returned by calls that completed without a prompt
+const noPrompt = dbus.ObjectPath("/")
+
+var (
+	// ErrNotFound is returned by Get for an item that is not in the keyring
+	ErrNotFound = errors.New("secret not found")
+
+	// ErrDismissed is returned when the user dismisses an unlock prompt
+	ErrDismissed = errors.New("keyring prompt dismissed")
+)
+
+// Secret is a secret as transferred by the Secret Service API
+type Secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// Client is a Secret Service client
+type Client struct {
+	conn    *dbus.Conn
+	service dbus.BusObject
+
+	// closeConn is whether Close closes conn, which New opened
+	closeConn bool
+}
+
+// New connects to the Secret Service on the session bus
+func New() (*Client, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the D-Bus session bus: %w", err)
+	}
+
+	c, err := NewWithConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.closeConn = true
+	return c, nil
+}
+
+// NewWithConn returns a client using an open bus connection, checking that
+// a Secret Service is running or can be started on the bus
+func NewWithConn(conn *dbus.Conn) (*Client, error) {
+	var running bool
+	if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, ServiceName).Store(&running); err != nil {
+		return nil, fmt.Errorf("failed to query the D-Bus session bus: %w", err)
+	}
+
+	if !running {
+		var activatable []string
+		if err := conn.BusObject().Call("org.freedesktop.DBus.ListActivatableNames", 0).Store(&activatable); err != nil {
+			return nil, fmt.Errorf("failed to query the D-Bus session bus: %w", err)
+		}
+		if !slices.Contains(activatable, ServiceName) {
+			return nil, errors.New("no Secret Service on the D-Bus session bus: is GNOME Keyring or KWallet running?")
+		}
+	}
+
+	return &Client{conn: conn, service: conn.Object(ServiceName, ServicePath)}, nil
+}
+
+// Close closes the bus connection if the client opened it
+func (c *Client) Close() error {
+	if c.closeConn {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// attributes identify the item of a secret name
+func attributes(name string) map[string]string {
+	return map[string]string{"service": Application, "username": name}
+}
+
+// Get returns the secret stored under name, unlocking it if needed, which
+// may show a password prompt
+func (c *Client) Get(ctx context.Context, name string) (string, error) {
+	var unlocked, locked []dbus.ObjectPath
+	if err := c.service.CallWithContext(ctx, ServiceIface+".SearchItems", 0, attributes(name)).Store(&unlocked, &locked); err != nil {
+		return "", fmt.Errorf("failed to search the keyring: %w", err)
+	}
+
+	if len(unlocked) == 0 && len(locked) > 0 {
+		var err error
+		if unlocked, err = c.unlock(ctx, locked[:1]); err != nil {
+			return "", err
+		}
+	}
+	if len(unlocked) == 0 {
+		return "", fmt.Errorf("%w in the keyring for '%s'", ErrNotFound, name)
+	}
+
+	session, err := c.openSession(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer c.closeSession(session)
+
+	var secret Secret
+	item := c.conn.Object(ServiceName, unlocked[0])
+	if err := item.CallWithContext(ctx, ItemIface+".GetSecret", 0, session).Store(&secret); err != nil {
+		return "", fmt.Errorf("failed to read '%s' from the keyring: %w", name, err)
+	}
+
+	if len(secret.Value) == 0 {
+		return "", fmt.Errorf("secret '%s' in the keyring is empty", name)
+	}
+	return string(secret.Value), nil
+}
+
+// Set stores a secret under name in the default collection, replacing any
+// existing one. label is shown by keyring managers such as Seahorse.
+func (c *Client) Set(ctx context.Context, name, label, value string) error {
+	var collection dbus.ObjectPath
+	if err := c.service.CallWithContext(ctx, ServiceIface+".ReadAlias", 0, DefaultAlias).Store(&collection); err != nil {
+		return fmt.Errorf("failed to find the default keyring: %w", err)
+	}
+	if collection == noPrompt {
+		return errors.New("the keyring has no default collection: create one in your keyring manager")
+	}
+
+	if _, err := c.unlock(ctx, []dbus.ObjectPath{collection}); err != nil {
+		return err
+	}
+
+	session, err := c.openSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.closeSession(session)
+
+	properties := map[string]dbus.Variant{
+		ItemIface + ".Label":      dbus.MakeVariant(label),
+		ItemIface + ".Attributes": dbus.MakeVariant(attributes(name)),
+	}
+	secret := Secret{Session: session, Value: []byte(value), ContentType: "text/plain"}
+
+	var item, prompt dbus.ObjectPath
+	obj := c.conn.Object(ServiceName, collection)
+	if err := obj.CallWithContext(ctx, CollectionIface+".CreateItem", 0, properties, secret, true).Store(&item, &prompt); err != nil {
+		return fmt.Errorf("failed to store '%s' in the keyring: %w", name, err)
+	}
+
+	if _, err := c.prompt(ctx, prompt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Delete removes the secrets stored under name
+func (c *Client) Delete(ctx context.Context, name string) error {
+	var unlocked, locked []dbus.ObjectPath
+	if err := c.service.CallWithContext(ctx, ServiceIface+".SearchItems", 0, attributes(name)).Store(&unlocked, &locked); err != nil {
+		return fmt.Errorf("failed to search the keyring: %w", err)
+	}
+
+	items := append(unlocked, locked...)
+	if len(items) == 0 {
+		return fmt.Errorf("%w in the keyring for '%s'", ErrNotFound, name)
+	}
+
+	for _, path := range items {
+		var prompt dbus.ObjectPath
+		if err := c.conn.Object(ServiceName, path).CallWithContext(ctx, ItemIface+".Delete", 0).Store(&prompt); err != nil {
+			return fmt.Errorf("failed to delete '%s' from the keyring: %w", name, err)
+		}
+		if _, err := c.prompt(ctx, prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openSession opens a session transferring secrets without encryption,
+// which the bus connection, private to the user, does not need
+func (c *Client) openSession(ctx context.Context) (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := c.service.CallWithContext(ctx, ServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return "", fmt.Errorf("failed to open a keyring session: %w", err)
+	}
+	return session, nil
+}
+
+func (c *Client) closeSession(session dbus.ObjectPath) {
+	c.conn.Object(ServiceName, session).Call(SessionIface+".Close", 0)
+}
+
+// unlock unlocks objects, which may show a password prompt, and returns
+// the unlocked objects
+func (c *Client) unlock(ctx context.Context, objects []dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := c.service.CallWithContext(ctx, ServiceIface+".Unlock", 0, objects).Store(&unlocked, &prompt); err != nil {
+		return nil, fmt.Errorf("failed to unlock the keyring: %w", err)
+	}
+
+	result, err := c.prompt(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if paths, ok := result.Value().([]dbus.ObjectPath); ok {
+		unlocked = append(unlocked, paths...)
+	}
+	return unlocked, nil
+}
+
+// prompt shows a prompt and waits for it to complete, returning its
+// result. A "/" prompt means none was needed.
+func (c *Client) prompt(ctx context.Context, prompt dbus.ObjectPath) (dbus.Variant, error) {
+	if prompt == noPrompt || prompt == "" {
+		return dbus.Variant{}, nil
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(PromptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := c.conn.AddMatchSignalContext(ctx, match...); err != nil {
+		return dbus.Variant{}, fmt.Errorf("failed to watch the keyring prompt: %w", err)
+	}
+	defer c.conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 1)
+	c.conn.Signal(signals)
+	defer c.conn.RemoveSignal(signals)
+
+	if err := c.conn.Object(ServiceName, prompt).CallWithContext(ctx, PromptIface+".Prompt", 0, "").Err; err != nil {
+		return dbus.Variant{}, fmt.Errorf("failed to show the keyring prompt: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return dbus.Variant{}, ctx.Err()
+		case sig := <-signals:
+			if sig.Path != prompt || sig.Name != PromptIface+".Completed" || len(sig.Body) != 2 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return dbus.Variant{}, ErrDismissed
+			}
+			result, _ := sig.Body[1].(dbus.Variant)
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package keyring_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/ironicbadger/jankey/internal/fakekeyring"
+	"github.com/ironicbadger/jankey/internal/keyring"
+)
+
+// startBus runs a private D-Bus session bus and returns its address
+func startBus(t *testing.T) string {
+	t.Helper()
+
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "session.conf")
+	err = os.WriteFile(config, []byte(fmt.Sprintf(`<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`, filepath.Join(dir, "bus"))), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+config, "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("dbus-daemon did not print its address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// connect opens a connection to the bus at address
+func connect(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// newTestKeyring serves a fake Secret Service on a private bus and returns
+// it with a client connected to it
+func newTestKeyring(t *testing.T, cfg fakekeyring.Config) (*fakekeyring.Service, *keyring.Client) {
+	t.Helper()
+
+	address := startBus(t)
+	service := fakekeyring.New(cfg)
+	if err := service.Export(connect(t, address)); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := keyring.NewWithConn(connect(t, address))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service, client
+}
+
+func TestNewWithoutService(t *testing.T) {
+	conn := connect(t, startBus(t))
+	if _, err := keyring.NewWithConn(conn); err == nil || !strings.Contains(err.Error(), "no Secret Service") {
+		t.Errorf("NewWithConn() error = %v, want no Secret Service", err)
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service, client := newTestKeyring(t, fakekeyring.Config{})
+
+	if _, err := client.Get(ctx, "tailscale/api-key"); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+
+	if err := client.Set(ctx, "tailscale/api-key", "jankey: API key", "tskey-api-one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Set(ctx, "tailscale/api-key", "jankey: API key", "tskey-api-two"); err != nil {
+		t.Fatal(err)
+	}
+
+	items := service.Items()
+	if len(items) != 1 || items[0].Label != "jankey: API key" || items[0].Attributes["service"] != keyring.Application ||
+		items[0].Attributes["username"] != "tailscale/api-key" {
+		t.Fatalf("items = %+v, want one replaced item", items)
+	}
+
+	if got, err := client.Get(ctx, "tailscale/api-key"); err != nil || got != "tskey-api-two" {
+		t.Errorf("Get() = %q, %v, want tskey-api-two", got, err)
+	}
+
+	// Items of other applications are not matched
+	service.Add("other", map[string]string{"service": "other", "username": "tailscale/api-key"}, "other")
+	if got, err := client.Get(ctx, "tailscale/api-key"); err != nil || got != "tskey-api-two" {
+		t.Errorf("Get() = %q, %v, want tskey-api-two", got, err)
+	}
+
+	if err := client.Delete(ctx, "tailscale/api-key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(ctx, "tailscale/api-key"); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLockedKeyring(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service, client := newTestKeyring(t, fakekeyring.Config{Locked: true})
+	service.Add("jankey: API key", map[string]string{"service": keyring.Application, "username": "tailscale/api-key"}, "tskey-api-locked")
+
+	if got, err := client.Get(ctx, "tailscale/api-key"); err != nil || got != "tskey-api-locked" {
+		t.Errorf("Get() = %q, %v, want the secret after unlocking", got, err)
+	}
+
+	service.Lock()
+	if err := client.Set(ctx, "tailscale/oauth-client-id", "jankey: OAuth client ID", "k123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if service.Prompts() != 2 {
+		t.Errorf("prompts = %d, want an unlock prompt for each call", service.Prompts())
+	}
+}
+
+func TestDismissedPrompt(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service, client := newTestKeyring(t, fakekeyring.Config{Locked: true, DismissPrompts: true})
+	service.Add("jankey: API key", map[string]string{"service": keyring.Application, "username": "tailscale/api-key"}, "tskey-api-locked")
+
+	if _, err := client.Get(ctx, "tailscale/api-key"); !errors.Is(err, keyring.ErrDismissed) {
+		t.Errorf("Get() error = %v, want ErrDismissed", err)
+	}
+	if err := client.Set(ctx, "tailscale/api-key", "jankey: API key", "tskey-api-new"); !errors.Is(err, keyring.ErrDismissed) {
+		t.Errorf("Set() error = %v, want ErrDismissed", err)
+	}
+}
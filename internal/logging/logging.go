@@ -0,0 +1,115 @@
+// Package logging provides the structured diagnostic logger shared by
+// jankey's commands and API clients. Diagnostics go to stderr so stdout only
+// carries the requested output, and secrets are masked in every record.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/redact"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// New creates a logger writing records at or above level to w in the given
+// format (FormatText or FormatJSON)
+func New(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}
+
+	switch format {
+	case FormatText, "":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+
+	return nil, fmt.Errorf("invalid log format '%s': must be 'text' or 'json'", format)
+}
+
+// Discard returns a logger that drops every record
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// OrDiscard returns logger, or a discarding logger if it is nil
+func OrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return Discard()
+	}
+	return logger
+}
+
+// ParseLevel parses a log level name: debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("invalid log level '%s': must be debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// redactAttr masks secrets in attribute values
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if redact.IsSensitiveField(a.Key) {
+			return slog.String(a.Key, redact.Secret(a.Value.String()))
+		}
+		return slog.String(a.Key, redact.Text(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case json.RawMessage:
+			return slog.Any(a.Key, json.RawMessage(redact.JSON(v)))
+		case error:
+			return slog.String(a.Key, redact.Text(v.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, redact.Text(v.String()))
+		}
+	}
+	return a
+}
+
+type requestIDKey struct{}
+
+// NewRequestID returns a random identifier correlating the log records of
+// one logical API request across retries
+func NewRequestID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a context carrying a request ID for logging
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Body returns an attribute holding a request or response body. JSON bodies
+// are logged as JSON with sensitive fields masked.
+func Body(key string, body []byte) slog.Attr {
+	trimmed := strings.TrimSpace(string(body))
+	if json.Valid([]byte(trimmed)) && trimmed != "" {
+		return slog.Any(key, json.RawMessage(trimmed))
+	}
+	return slog.String(key, trimmed)
+}
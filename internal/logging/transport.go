@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ironicbadger/jankey/internal/retry"
+)
+
+// Transport is an http.RoundTripper that logs every round trip at debug
+// level with its request ID, method, URL, attempt, status and latency
+type Transport struct {
+	// Base performs the requests, defaults to http.DefaultTransport
+	Base http.RoundTripper
+
+	Logger *slog.Logger
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+
+	ctx := req.Context()
+	attrs := []slog.Attr{
+		slog.String("request_id", RequestID(ctx)),
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+		slog.Int("attempt", retry.AttemptNumber(ctx)),
+		slog.Duration("latency", time.Since(start)),
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+		OrDiscard(t.Logger).LogAttrs(ctx, slog.LevelDebug, "api request failed", attrs...)
+		return nil, err
+	}
+
+	attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	OrDiscard(t.Logger).LogAttrs(ctx, slog.LevelDebug, "api request", attrs...)
+	return resp, nil
+}
+
+// RetryLogger returns a retry.Policy callback that logs retries at info level
+func RetryLogger(ctx context.Context, logger *slog.Logger) func(retry.Attempt) {
+	return func(a retry.Attempt) {
+		OrDiscard(logger).LogAttrs(ctx, slog.LevelInfo, "retrying api request",
+			slog.String("request_id", RequestID(ctx)),
+			slog.Int("attempt", a.Number+1),
+			slog.Int("max_retries", a.MaxRetries),
+			slog.Duration("wait", a.Wait),
+			slog.Any("reason", a.Reason),
+		)
+	}
+}
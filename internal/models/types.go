@@ -4,29 +4,139 @@ import "time"
 
 // Config represents the application configuration
 type Config struct {
-	APIKey           APIKeyConfig     `yaml:"api_key"`
-	OAuth            OAuthConfig      `yaml:"oauth"`
-	AuthKeyDefaults  AuthKeyDefaults  `yaml:"auth_key_defaults"`
+	Version         int                `yaml:"version,omitempty"`
+	APIKey          APIKeyConfig       `yaml:"api_key"`
+	OAuth           OAuthConfig        `yaml:"oauth"`
+	AuthKeyDefaults AuthKeyDefaults    `yaml:"auth_key_defaults"`
+	API             APIConfig          `yaml:"api,omitempty"`
+	Output          OutputConfig       `yaml:"output,omitempty"`
+	Pass            PassConfig         `yaml:"pass,omitempty"`
+	Presets         map[string]Preset  `yaml:"presets,omitempty"`
+	AuthMethod      string             `yaml:"auth_method,omitempty"`
+	Profiles        map[string]Profile `yaml:"profiles,omitempty"`
+	CurrentProfile  string             `yaml:"current_profile,omitempty"`
+
+	// ActiveProfile is the name of the profile applied to the config, if any
+	ActiveProfile string `yaml:"-"`
+}
+
+// Authentication methods
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodOAuth  = "oauth"
+)
+
+// Profile is a named set of settings, such as for a different tailnet or
+// credential, applied over the top-level config. Unset fields keep the
+// top-level values; credential and auth_key_defaults sections replace them
+// as a whole.
+type Profile struct {
+	AuthMethod      string           `yaml:"auth_method,omitempty"`
+	APIKey          *APIKeyConfig    `yaml:"api_key,omitempty"`
+	OAuth           *OAuthConfig     `yaml:"oauth,omitempty"`
+	API             APIConfig        `yaml:"api,omitempty"`
+	AuthKeyDefaults *AuthKeyDefaults `yaml:"auth_key_defaults,omitempty"`
+	Output          OutputConfig     `yaml:"output,omitempty"`
+}
+
+// Preset is a named set of auth key settings selected with --preset. Unset
+// fields fall back to auth_key_defaults.
+type Preset struct {
+	Ephemeral     *bool    `yaml:"ephemeral,omitempty"`
+	Reusable      *bool    `yaml:"reusable,omitempty"`
+	Preauthorized *bool    `yaml:"preauthorized,omitempty"`
+	ExpiryDays    int      `yaml:"expiry_days,omitempty"`
+	Tags          []string `yaml:"tags,omitempty"`
+
+	// Description is a Go text/template for the auth key description
+	Description string `yaml:"description,omitempty"`
+}
+
+// OutputConfig holds settings for where generated auth keys are written
+type OutputConfig struct {
+	// StorePass is a pass path to store generated auth keys at
+	StorePass string `yaml:"store_pass,omitempty"`
+
+	// StoreKeyring is a keyring item name to store generated auth keys
+	// under
+	StoreKeyring string `yaml:"store_keyring,omitempty"`
+}
+
+// PassConfig selects the password store tool used for pass paths
+type PassConfig struct {
+	// Backend is pass, gopass, passage, or auto (the default) for the
+	// first one installed
+	Backend string `yaml:"backend,omitempty"`
+}
+
+// APIConfig holds Tailscale API endpoint settings
+type APIConfig struct {
+	BaseURL string `yaml:"base_url,omitempty"`
+	Tailnet string `yaml:"tailnet,omitempty"`
 }
 
 // APIKeyConfig holds API key settings
 type APIKeyConfig struct {
 	PassPathAPIKey string `yaml:"pass_path_api_key"`
+
+	// FilePathAPIKey is a file holding the API key, for the file source
+	FilePathAPIKey string `yaml:"file_path_api_key,omitempty"`
+
+	// CommandAPIKey prints the API key, for the command source
+	CommandAPIKey CommandConfig `yaml:"command_api_key,omitempty"`
+
+	// KeyringAPIKey names the API key in the keyring, for the keyring
+	// source
+	KeyringAPIKey string `yaml:"keyring_api_key,omitempty"`
+
+	// Sources are the credential sources tried in order, default pass,
+	// env, file, command then keyring
+	Sources []string `yaml:"sources,omitempty"`
 }
 
 // OAuthConfig holds OAuth client credential paths
 type OAuthConfig struct {
 	PassPathClientID     string `yaml:"pass_path_client_id"`
 	PassPathClientSecret string `yaml:"pass_path_client_secret"`
+
+	// Files holding the client ID and secret, for the file source
+	FilePathClientID     string `yaml:"file_path_client_id,omitempty"`
+	FilePathClientSecret string `yaml:"file_path_client_secret,omitempty"`
+
+	// Commands printing the client ID and secret, for the command source
+	CommandClientID     CommandConfig `yaml:"command_client_id,omitempty"`
+	CommandClientSecret CommandConfig `yaml:"command_client_secret,omitempty"`
+
+	// Names of the client ID and secret in the keyring, for the keyring
+	// source
+	KeyringClientID     string `yaml:"keyring_client_id,omitempty"`
+	KeyringClientSecret string `yaml:"keyring_client_secret,omitempty"`
+
+	// Sources are the credential sources tried in order for both the
+	// client ID and secret, default pass, env, file, command then keyring
+	Sources []string `yaml:"sources,omitempty"`
+}
+
+// CommandConfig is an external command whose output is a credential
+type CommandConfig struct {
+	// Argv is the program and its arguments, run without a shell
+	Argv []string `yaml:"argv,omitempty"`
+
+	// Timeout is a duration such as "10s", default 30s
+	Timeout string `yaml:"timeout,omitempty"`
+
+	// JSONField is a dotted path to the credential in JSON output, such as
+	// "data.api_key"
+	JSONField string `yaml:"json_field,omitempty"`
 }
 
 // AuthKeyDefaults holds default settings for auth key generation
 type AuthKeyDefaults struct {
-	Ephemeral      bool     `yaml:"ephemeral"`
-	Reusable       bool     `yaml:"reusable"`
-	Preauthorized  bool     `yaml:"preauthorized"`
-	ExpiryDays     int      `yaml:"expiry_days"`
-	Tags           []string `yaml:"tags"`
+	Ephemeral     bool     `yaml:"ephemeral"`
+	Reusable      bool     `yaml:"reusable"`
+	Preauthorized bool     `yaml:"preauthorized"`
+	ExpiryDays    int      `yaml:"expiry_days"`
+	Tags          []string `yaml:"tags"`
 }
 
 // OAuthTokenResponse represents the OAuth token response from Tailscale
@@ -34,13 +144,14 @@ type OAuthTokenResponse struct {
 	AccessToken string `json:"access_token"`
 	TokenType   string `json:"token_type"`
 	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
 }
 
 // AuthKeyRequest represents the request to create an auth key
 type AuthKeyRequest struct {
-	Capabilities   Capabilities `json:"capabilities"`
-	ExpirySeconds  int64        `json:"expirySeconds"`
-	Description    string       `json:"description,omitempty"`
+	Capabilities  Capabilities `json:"capabilities"`
+	ExpirySeconds int64        `json:"expirySeconds"`
+	Description   string       `json:"description,omitempty"`
 }
 
 // Capabilities defines the auth key capabilities
@@ -70,19 +181,20 @@ type AuthKeyResponse struct {
 	Capabilities Capabilities `json:"capabilities"`
 }
 
-// AuthKeyOutput represents the JSON output format
+// AuthKeyOutput represents a generated auth key as written by the output
+// formats
 type AuthKeyOutput struct {
-	Key          string                   `json:"key"`
-	ID           string                   `json:"id"`
-	Created      string                   `json:"created"`
-	Expires      string                   `json:"expires"`
-	Capabilities AuthKeyOutputCapabilities `json:"capabilities"`
-	Tags         []string                 `json:"tags"`
+	Key          string                    `json:"key" yaml:"key"`
+	ID           string                    `json:"id" yaml:"id"`
+	Created      string                    `json:"created" yaml:"created"`
+	Expires      string                    `json:"expires" yaml:"expires"`
+	Capabilities AuthKeyOutputCapabilities `json:"capabilities" yaml:"capabilities"`
+	Tags         []string                  `json:"tags" yaml:"tags"`
 }
 
 // AuthKeyOutputCapabilities simplified capabilities for output
 type AuthKeyOutputCapabilities struct {
-	Ephemeral     bool `json:"ephemeral"`
-	Reusable      bool `json:"reusable"`
-	Preauthorized bool `json:"preauthorized"`
+	Ephemeral     bool `json:"ephemeral" yaml:"ephemeral"`
+	Reusable      bool `json:"reusable" yaml:"reusable"`
+	Preauthorized bool `json:"preauthorized" yaml:"preauthorized"`
 }
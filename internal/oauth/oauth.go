@@ -1,128 +1,157 @@
 package oauth
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/ironicbadger/jankey/internal/logging"
 	"github.com/ironicbadger/jankey/internal/models"
-)
-
-const (
-	TailscaleOAuthURL = "https://api.tailscale.com/api/v2/oauth/token"
+	"github.com/ironicbadger/jankey/internal/redact"
+	"github.com/ironicbadger/jankey/internal/retry"
+	"github.com/ironicbadger/jankey/internal/tailscale"
 )
 
 // Client represents an OAuth client for Tailscale API
 type Client struct {
 	clientID     string
 	clientSecret string
+	baseURL      string
+	retry        retry.Policy
 	httpClient   *http.Client
-	verbose      bool
+	log          *slog.Logger
+}
+
+// Options configures an OAuth client
+type Options struct {
+	// BaseURL of the API, defaults to tailscale.DefaultBaseURL
+	BaseURL string
+
+	// Retry policy for failed requests, defaults to retry.DefaultPolicy
+	Retry retry.Policy
+
+	// Logger for diagnostics, nil discards them
+	Logger *slog.Logger
 }
 
 // New creates a new OAuth client
-func New(clientID, clientSecret string, verbose bool) *Client {
+func New(clientID, clientSecret string, opts Options) *Client {
+	retryPolicy := opts.Retry
+	if retryPolicy == (retry.Policy{}) {
+		retryPolicy = retry.DefaultPolicy()
+	}
+
+	log := logging.OrDiscard(opts.Logger)
+
 	return &Client{
 		clientID:     clientID,
 		clientSecret: clientSecret,
+		baseURL:      tailscale.NormalizeBaseURL(opts.BaseURL),
+		retry:        retryPolicy,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: &logging.Transport{Logger: log},
 		},
-		verbose: verbose,
+		log: log,
 	}
 }
 
+// tokenURL returns the OAuth token endpoint URL
+func (c *Client) tokenURL() string {
+	return c.baseURL + "/api/v2/oauth/token"
+}
+
+// AuthKeyScopes are the OAuth scopes that allow creating auth keys
+var AuthKeyScopes = []string{"all", "auth_keys", "devices"}
+
+// CanCreateAuthKeys reports whether a space-separated scope list includes
+// one of AuthKeyScopes
+func CanCreateAuthKeys(scope string) bool {
+	for _, s := range strings.Fields(scope) {
+		if slices.Contains(AuthKeyScopes, s) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetAccessToken exchanges OAuth credentials for an access token
-func (c *Client) GetAccessToken() (string, error) {
+func (c *Client) GetAccessToken(ctx context.Context) (string, error) {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// Token exchanges OAuth credentials for an access token, returning the full
+// token response including the granted scopes
+func (c *Client) Token(ctx context.Context) (*models.OAuthTokenResponse, error) {
 	// Prepare form data
 	formData := url.Values{}
 	formData.Set("client_id", c.clientID)
 	formData.Set("client_secret", c.clientSecret)
 	formData.Set("grant_type", "client_credentials")
 
-	if c.verbose {
-		fmt.Println("→ Requesting OAuth access token from Tailscale API...")
-		fmt.Printf("  URL: %s\n", TailscaleOAuthURL)
-		fmt.Printf("  Client ID: %s\n", c.redactClientID())
-	}
+	tokenURL := c.tokenURL()
 
-	// Create request
-	req, err := http.NewRequest("POST", TailscaleOAuthURL, bytes.NewBufferString(formData.Encode()))
-	if err != nil {
-		return "", fmt.Errorf("failed to create OAuth request: %w", err)
-	}
+	requestID := logging.NewRequestID()
+	ctx = logging.WithRequestID(ctx, requestID)
+	log := c.log.With("request_id", requestID)
 
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	log.Debug("requesting OAuth access token", "url", tokenURL, "client_id", redact.Partial(c.clientID))
 
-	// Execute request with retry logic
-	resp, err := c.executeWithRetry(req, 3)
+	// Execute request with retry logic, building a fresh request per attempt
+	resp, err := c.retry.Do(ctx, c.httpClient, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(formData.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OAuth request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	}, logging.RetryLogger(ctx, c.log))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read OAuth response: %w", err)
+		return nil, fmt.Errorf("failed to read OAuth response: %w", err)
 	}
 
 	// Check for errors
 	if resp.StatusCode != http.StatusOK {
-		return "", c.handleOAuthError(resp.StatusCode, body)
+		return nil, c.handleOAuthError(resp.StatusCode, body)
 	}
 
 	// Parse response
 	var tokenResp models.OAuthTokenResponse
 	if err := json.Unmarshal(body, &tokenResp); err != nil {
-		return "", fmt.Errorf("failed to parse OAuth response: %w", err)
+		return nil, fmt.Errorf("failed to parse OAuth response: %w", err)
 	}
 
-	if c.verbose {
-		fmt.Printf("✓ OAuth access token obtained (expires in %d seconds)\n", tokenResp.ExpiresIn)
-	}
+	log.Debug("OAuth access token obtained", "expires_in", tokenResp.ExpiresIn, "scope", tokenResp.Scope)
 
-	return tokenResp.AccessToken, nil
+	return &tokenResp, nil
 }
 
-// executeWithRetry executes an HTTP request with exponential backoff retry
-func (c *Client) executeWithRetry(req *http.Request, maxRetries int) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
-			waitTime := time.Duration(1<<uint(attempt-1)) * time.Second
-			if c.verbose {
-				fmt.Printf("  Retry attempt %d/%d after %v...\n", attempt, maxRetries, waitTime)
-			}
-			time.Sleep(waitTime)
-		}
-
-		resp, err = c.httpClient.Do(req)
-		if err == nil {
-			return resp, nil
-		}
-
-		// Don't retry on non-network errors
-		if !isNetworkError(err) {
-			break
-		}
-
-		if c.verbose {
-			fmt.Printf("  Network error: %v\n", err)
-		}
-	}
-
-	return nil, fmt.Errorf("failed after %d retries: %w", maxRetries, err)
+// handleOAuthError formats OAuth API errors as a tailscale.APIError
+func (c *Client) handleOAuthError(statusCode int, body []byte) error {
+	return &tailscale.APIError{StatusCode: statusCode, Err: formatOAuthError(statusCode, body)}
 }
 
-// handleOAuthError formats OAuth API errors
-func (c *Client) handleOAuthError(statusCode int, body []byte) error {
+// formatOAuthError describes an OAuth error response
+func formatOAuthError(statusCode int, body []byte) error {
 	var errorMsg string
 
 	// Try to parse error response
@@ -158,31 +187,3 @@ func (c *Client) handleOAuthError(statusCode int, body []byte) error {
 		return fmt.Errorf("OAuth request failed (%d): %s", statusCode, errorMsg)
 	}
 }
-
-// redactClientID returns a redacted version of the client ID for logging
-func (c *Client) redactClientID() string {
-	if len(c.clientID) <= 8 {
-		return "****"
-	}
-	return c.clientID[:4] + "****" + c.clientID[len(c.clientID)-4:]
-}
-
-// isNetworkError checks if an error is network-related (retryable)
-func isNetworkError(err error) bool {
-	if err == nil {
-		return false
-	}
-	// Check for common network errors
-	errStr := err.Error()
-	return contains(errStr, "timeout") ||
-		contains(errStr, "connection refused") ||
-		contains(errStr, "connection reset") ||
-		contains(errStr, "no such host") ||
-		contains(errStr, "temporary failure")
-}
-
-// contains is a simple case-insensitive string contains check
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
-		(len(s) > 0 && (s[:len(substr)] == substr || contains(s[1:], substr))))
-}
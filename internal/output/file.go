@@ -0,0 +1,228 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileMode is the permission mode of new files written with a key
+const FileMode os.FileMode = 0o600
+
+// FileOptions configure WriteFile and UpdateEnvFile
+type FileOptions struct {
+	// UID and GID to give the file, -1 keeps those of an existing file
+	UID int
+	GID int
+}
+
+// DefaultFileOptions returns options that keep the owner and group of an
+// existing file
+func DefaultFileOptions() FileOptions {
+	return FileOptions{UID: -1, GID: -1}
+}
+
+// ParseOwner splits an OWNER[:GROUP] specification
+func ParseOwner(spec string) (owner, group string) {
+	owner, group, _ = strings.Cut(spec, ":")
+	return owner, group
+}
+
+// LookupOwner resolves a user and group, by name or numeric ID, for
+// FileOptions. Empty names leave the owner or group unchanged.
+func LookupOwner(owner, group string) (FileOptions, error) {
+	opts := DefaultFileOptions()
+
+	if owner != "" {
+		uid, err := strconv.Atoi(owner)
+		if err != nil {
+			u, lookupErr := user.Lookup(owner)
+			if lookupErr != nil {
+				return opts, fmt.Errorf("unknown owner '%s': %w", owner, lookupErr)
+			}
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return opts, fmt.Errorf("owner '%s' has non-numeric uid %s", owner, u.Uid)
+			}
+		}
+		opts.UID = uid
+	}
+
+	if group != "" {
+		gid, err := strconv.Atoi(group)
+		if err != nil {
+			g, lookupErr := user.LookupGroup(group)
+			if lookupErr != nil {
+				return opts, fmt.Errorf("unknown group '%s': %w", group, lookupErr)
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return opts, fmt.Errorf("group '%s' has non-numeric gid %s", group, g.Gid)
+			}
+		}
+		opts.GID = gid
+	}
+
+	return opts, nil
+}
+
+// WriteFile atomically replaces path with data. The data is written to a
+// temporary file in the same directory which is renamed over path, so
+// readers never see a partial key. An existing file keeps its mode, and
+// its owner and group unless opts sets them; a new file gets FileMode.
+// Symlinks and non-regular files at path are refused.
+func WriteFile(path string, data []byte, opts FileOptions) (err error) {
+	if err := checkRegularFile(path); err != nil {
+		return err
+	}
+
+	mode := FileMode
+	existing, err := os.Stat(path)
+	if err == nil {
+		mode = existing.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := tmp.Chmod(mode); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+
+	if existing != nil {
+		info, err := tmp.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", tmp.Name(), err)
+		}
+		opts = inheritOwner(opts, existing, info)
+	}
+
+	if opts.UID != -1 || opts.GID != -1 {
+		if err := tmp.Chown(opts.UID, opts.GID); err != nil {
+			return fmt.Errorf("failed to set owner of %s: %w", path, err)
+		}
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// UpdateEnvFile sets name to value in the dotenv file at path, preserving
+// all other lines. An existing assignment, optionally prefixed by
+// "export", is replaced in place; otherwise the assignment is appended. A
+// missing file is created. The file is written with WriteFile.
+func UpdateEnvFile(path, name, value string, opts FileOptions) error {
+	if err := ValidateEnvVar(name); err != nil {
+		return err
+	}
+
+	if err := checkRegularFile(path); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return WriteFile(path, setEnvLine(data, name, value), opts)
+}
+
+// setEnvLine replaces or appends the assignment of name in dotenv data,
+// keeping the line endings of the file
+func setEnvLine(data []byte, name, value string) []byte {
+	assignment := regexp.MustCompile(`^(\s*(?:export\s+)?)` + regexp.QuoteMeta(name) + `\s*=`)
+
+	// Lines without an ending, and the appended line, use the ending of the
+	// first line
+	eol := "\n"
+	if i := bytes.IndexByte(data, '\n'); i > 0 && data[i-1] == '\r' {
+		eol = "\r\n"
+	}
+
+	var out bytes.Buffer
+	found := false
+
+	for _, raw := range strings.SplitAfter(string(data), "\n") {
+		if raw == "" {
+			continue
+		}
+		line := strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r")
+		ending := raw[len(line):]
+		if ending == "" {
+			ending = eol
+		}
+
+		if m := assignment.FindStringSubmatch(line); m != nil {
+			if found {
+				// Drop duplicate assignments so the new value wins everywhere
+				continue
+			}
+			line = m[1] + name + "=" + DotenvQuote(value)
+			found = true
+		}
+		out.WriteString(line + ending)
+	}
+
+	if !found {
+		out.WriteString(name + "=" + DotenvQuote(value) + eol)
+	}
+
+	return out.Bytes()
+}
+
+// checkRegularFile refuses to write through a symlink or over anything
+// other than a regular file
+func checkRegularFile(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return fmt.Errorf("refusing to write %s: it is a symlink", path)
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("refusing to write %s: not a regular file", path)
+	}
+
+	return nil
+}
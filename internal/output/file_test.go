@@ -0,0 +1,190 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "authkey")
+	if err := os.WriteFile(path, []byte("old"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFile(path, []byte("tskey-auth-k1-new\n"), DefaultFileOptions()); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "tskey-auth-k1-new\n" {
+		t.Errorf("content = %q", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want the existing file's 0640", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the written file", len(entries))
+	}
+
+	// New files are readable only by their owner
+	created := filepath.Join(filepath.Dir(path), "new")
+	if err := WriteFile(created, []byte("tskey-auth-k2\n"), DefaultFileOptions()); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if info, err := os.Stat(created); err != nil || info.Mode().Perm() != FileMode {
+		t.Errorf("new file mode = %v, %v, want %v", info.Mode().Perm(), err, FileMode)
+	}
+}
+
+func TestWriteFileKeepsOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of a file needs root")
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("TS_AUTHKEY=old\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(path, 1234, 5678); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := UpdateEnvFile(path, "TS_AUTHKEY", "tskey-auth-new", DefaultFileOptions()); err != nil {
+		t.Fatalf("UpdateEnvFile() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat := info.Sys().(*syscall.Stat_t)
+	if stat.Uid != 1234 || stat.Gid != 5678 || info.Mode().Perm() != 0o640 {
+		t.Errorf("owner = %d:%d, mode %v, want 1234:5678 and 0640 kept", stat.Uid, stat.Gid, info.Mode().Perm())
+	}
+}
+
+func TestWriteFileRefusesSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.WriteFile(target, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	if err := WriteFile(link, []byte("new"), DefaultFileOptions()); err == nil || !strings.Contains(err.Error(), "symlink") {
+		t.Errorf("WriteFile() error = %v, want symlink error", err)
+	}
+	if err := UpdateEnvFile(link, "TS_AUTHKEY", "new", DefaultFileOptions()); err == nil {
+		t.Error("UpdateEnvFile() error = nil, want symlink error")
+	}
+
+	if data, _ := os.ReadFile(target); string(data) != "keep" {
+		t.Errorf("symlink target modified: %q", data)
+	}
+
+	if err := WriteFile(dir, []byte("new"), DefaultFileOptions()); err == nil {
+		t.Error("WriteFile() on a directory error = nil, want error")
+	}
+}
+
+func TestUpdateEnvFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+	}{
+		{
+			name:     "replace",
+			existing: "# compose settings\nTZ=UTC\nTS_AUTHKEY=tskey-auth-old\nTS_HOSTNAME=web\n",
+			want:     "# compose settings\nTZ=UTC\nTS_AUTHKEY=tskey-auth-new\nTS_HOSTNAME=web\n",
+		},
+		{
+			name:     "keep export prefix and drop duplicates",
+			existing: "export TS_AUTHKEY='old'\nTS_AUTHKEY=older\n",
+			want:     "export TS_AUTHKEY=tskey-auth-new\n",
+		},
+		{
+			name:     "append",
+			existing: "TZ=UTC",
+			want:     "TZ=UTC\nTS_AUTHKEY=tskey-auth-new\n",
+		},
+		{
+			name:     "similar name untouched",
+			existing: "TS_AUTHKEY_FILE=/run/secrets/key\n",
+			want:     "TS_AUTHKEY_FILE=/run/secrets/key\nTS_AUTHKEY=tskey-auth-new\n",
+		},
+		{
+			name:     "keep CRLF line endings",
+			existing: "TZ=UTC\r\nTS_AUTHKEY=old\r\nTS_HOSTNAME=web",
+			want:     "TZ=UTC\r\nTS_AUTHKEY=tskey-auth-new\r\nTS_HOSTNAME=web\r\n",
+		},
+		{
+			name:     "append with CRLF",
+			existing: "TZ=UTC\r\n",
+			want:     "TZ=UTC\r\nTS_AUTHKEY=tskey-auth-new\r\n",
+		},
+		{
+			name: "missing file",
+			want: "TS_AUTHKEY=tskey-auth-new\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := UpdateEnvFile(path, "TS_AUTHKEY", "tskey-auth-new", DefaultFileOptions()); err != nil {
+				t.Fatalf("UpdateEnvFile() error = %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("content = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupOwner(t *testing.T) {
+	opts, err := LookupOwner(ParseOwner("1000:1001"))
+	if err != nil || opts.UID != 1000 || opts.GID != 1001 {
+		t.Errorf("LookupOwner(1000:1001) = %+v, %v", opts, err)
+	}
+
+	opts, err = LookupOwner(ParseOwner("1000"))
+	if err != nil || opts.UID != 1000 || opts.GID != -1 {
+		t.Errorf("LookupOwner(1000) = %+v, %v", opts, err)
+	}
+
+	if _, err := LookupOwner("no-such-user-jankey", ""); err == nil {
+		t.Error("LookupOwner(unknown) error = nil, want error")
+	}
+}
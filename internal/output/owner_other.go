@@ -0,0 +1,10 @@
+//go:build !unix
+
+package output
+
+import "io/fs"
+
+// inheritOwner returns opts unchanged, as files have no numeric owner
+func inheritOwner(opts FileOptions, existing, created fs.FileInfo) FileOptions {
+	return opts
+}
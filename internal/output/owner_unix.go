@@ -0,0 +1,29 @@
+//go:build unix
+
+package output
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// inheritOwner returns opts with an unset owner or group taken from the
+// existing file, where it differs from that of the new file
+func inheritOwner(opts FileOptions, existing, created fs.FileInfo) FileOptions {
+	old, ok := existing.Sys().(*syscall.Stat_t)
+	if !ok {
+		return opts
+	}
+	cur, ok := created.Sys().(*syscall.Stat_t)
+	if !ok {
+		return opts
+	}
+
+	if opts.UID == -1 && old.Uid != cur.Uid {
+		opts.UID = int(old.Uid)
+	}
+	if opts.GID == -1 && old.Gid != cur.Gid {
+		opts.GID = int(old.Gid)
+	}
+	return opts
+}
@@ -0,0 +1,180 @@
+package pass
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Backend names, as used in the pass.backend config key
+const (
+	BackendAuto    = "auto"
+	BackendPass    = "pass"
+	BackendGopass  = "gopass"
+	BackendPassage = "passage"
+)
+
+// Backends returns the names of the password store tools, in the order
+// BackendAuto looks for them
+func Backends() []string {
+	return []string{BackendPass, BackendGopass, BackendPassage}
+}
+
+// IsBackend reports whether name is BackendAuto or a backend name
+func IsBackend(name string) bool {
+	if name == BackendAuto {
+		return true
+	}
+	for _, b := range Backends() {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// backend describes the command lines and errors of a password store tool.
+// All of them name entries by the same relative paths.
+type backend struct {
+	name string
+	url  string
+
+	// showArgs print an entry, the first line being the secret
+	showArgs func(path string) []string
+
+	// insertArgs read a multi-line entry from stdin
+	insertArgs func(path string, force bool) []string
+
+	// notFound is part of the tool's stderr for a missing entry, compared
+	// without case
+	notFound string
+
+	// usesGPG is whether the tool decrypts entries with the GPG agent
+	usesGPG bool
+
+	// checkStore checks that the store is initialized, returning its
+	// directory
+	checkStore func() (string, error)
+}
+
+// passInsertArgs are the insert arguments shared by pass, gopass and
+// passage
+func passInsertArgs(path string, force bool) []string {
+	args := []string{"insert", "-m"}
+	if force {
+		args = append(args, "-f")
+	}
+	return append(args, path)
+}
+
+var backends = map[string]*backend{
+	BackendPass: {
+		name:       BackendPass,
+		url:        "https://www.passwordstore.org/",
+		showArgs:   func(path string) []string { return []string{"show", path} },
+		insertArgs: passInsertArgs,
+		notFound:   "is not in the password store",
+		usesGPG:    true,
+		checkStore: CheckStore,
+	},
+	BackendGopass: {
+		name: BackendGopass,
+		url:  "https://www.gopass.pw/",
+		// Without -n gopass parses entries, such as reformatting YAML
+		showArgs:   func(path string) []string { return []string{"show", "-n", path} },
+		insertArgs: passInsertArgs,
+		notFound:   "entry is not in the password store",
+		usesGPG:    true,
+		checkStore: checkGopassStore,
+	},
+	BackendPassage: {
+		name:       BackendPassage,
+		url:        "https://github.com/FiloSottile/passage",
+		showArgs:   func(path string) []string { return []string{"show", path} },
+		insertArgs: passInsertArgs,
+		notFound:   "is not in the password store",
+		checkStore: checkPassageStore,
+	},
+}
+
+// lookupBackend returns the backend named name, or for BackendAuto or an
+// empty name the first one installed, and the path of its binary
+func lookupBackend(name string) (*backend, string, error) {
+	if name != "" && name != BackendAuto {
+		b, ok := backends[name]
+		if !ok {
+			return nil, "", fmt.Errorf("unknown password store '%s': must be %s or %s", name, BackendAuto, strings.Join(Backends(), ", "))
+		}
+		binPath, err := exec.LookPath(b.name)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s not found: please install %s (%s)", b.name, b.name, b.url)
+		}
+		return b, binPath, nil
+	}
+
+	for _, name := range Backends() {
+		if binPath, err := exec.LookPath(name); err == nil {
+			return backends[name], binPath, nil
+		}
+	}
+	return nil, "", fmt.Errorf("pass not found: please install pass (%s), gopass or passage", backends[BackendPass].url)
+}
+
+// checkGopassStore checks that gopass has a root store
+func checkGopassStore() (string, error) {
+	out, err := exec.Command("gopass", "config", "mounts.path").Output()
+	dir := strings.TrimSpace(string(out))
+	if err != nil || dir == "" {
+		return "", errors.New("gopass has no password store: run 'gopass setup'")
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return dir, fmt.Errorf("gopass password store at %s is missing: run 'gopass setup'", dir)
+	}
+	return dir, nil
+}
+
+// PassageStoreDir returns the passage store directory, PASSAGE_DIR or
+// ~/.passage/store
+func PassageStoreDir() (string, error) {
+	if dir := os.Getenv("PASSAGE_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".passage", "store"), nil
+}
+
+// checkPassageStore checks that the passage store exists and that there is
+// an age identities file to decrypt it with
+func checkPassageStore() (string, error) {
+	dir, err := PassageStoreDir()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return dir, fmt.Errorf("passage store at %s does not exist: run 'passage init'", dir)
+		}
+		return dir, fmt.Errorf("failed to read passage store: %w", err)
+	}
+
+	identities := os.Getenv("PASSAGE_IDENTITIES_FILE")
+	if identities == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return dir, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		identities = filepath.Join(home, ".passage", "identities")
+	}
+	if _, err := os.Stat(identities); err != nil {
+		return dir, fmt.Errorf("passage has no age identities at %s: passage cannot decrypt secrets without them", identities)
+	}
+
+	return dir, nil
+}
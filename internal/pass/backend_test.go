@@ -0,0 +1,167 @@
+package pass
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeTool installs a shell script named name as the only program on PATH
+func fakeTool(t *testing.T, dir, name, script string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewAuto(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+
+	if _, err := New(BackendAuto); err == nil || !strings.Contains(err.Error(), "pass not found") {
+		t.Errorf("New() error = %v without any tool", err)
+	}
+
+	fakeTool(t, dir, "passage", "exit 0\n")
+	fakeTool(t, dir, "gopass", "exit 0\n")
+	if c, err := New(""); err != nil || c.Name() != BackendGopass {
+		t.Errorf("New() = %v, %v, want gopass before passage", c, err)
+	}
+
+	if c, err := New(BackendPassage); err != nil || c.Name() != BackendPassage || c.UsesGPG() {
+		t.Errorf("New(passage) = %v, %v", c, err)
+	}
+	if _, err := New(BackendPass); err == nil || !strings.Contains(err.Error(), "please install pass") {
+		t.Errorf("New(pass) error = %v, want not installed", err)
+	}
+	if _, err := New("keepass"); err == nil || !strings.Contains(err.Error(), "unknown password store") {
+		t.Errorf("New(keepass) error = %v", err)
+	}
+}
+
+func TestClientBackends(t *testing.T) {
+	tests := []struct {
+		backend  string
+		show     string
+		notFound string
+	}{
+		{BackendPass, "show tailscale/api-key", "Error: tailscale/missing is not in the password store."},
+		{BackendGopass, "show -n tailscale/api-key", "Error: failed to retrieve secret 'tailscale/missing': Entry is not in the password store"},
+		{BackendPassage, "show tailscale/api-key", "Error: tailscale/missing is not in the password store."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			// The fake prints its arguments for the entry, fails with the
+			// tool's message for missing entries and records inserts
+			log := filepath.Join(dir, "insert.log")
+			fakeTool(t, dir, tt.backend, `case "$*" in
+*missing*) echo "`+tt.notFound+`" >&2; exit 1 ;;
+*broken*) echo "gpg: decryption failed" >&2; exit 2 ;;
+insert*) echo "$*" > `+log+`; cat >> `+log+` ;;
+*) echo "$*" ;;
+esac
+`)
+
+			c, err := New(tt.backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, err := c.Get("tailscale/api-key"); err != nil || got != tt.show {
+				t.Errorf("Get() = %q, %v, want %q", got, err, tt.show)
+			}
+			if _, err := c.Get("tailscale/api-key#client_id"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() error = %v for a missing field, want ErrNotFound", err)
+			}
+			if _, err := c.Get("tailscale/missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() error = %v, want ErrNotFound", err)
+			}
+			if _, err := c.Get("tailscale/broken"); err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), tt.backend+": gpg") {
+				t.Errorf("Get() error = %v, want a %s failure", err, tt.backend)
+			}
+
+			if err := c.Overwrite("tailscale/authkey", "tskey-auth"); err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := os.ReadFile(log); string(data) != "insert -m -f tailscale/authkey\ntskey-auth" {
+				t.Errorf("Overwrite() ran %q", data)
+			}
+		})
+	}
+}
+
+func TestCheckPassageStore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PASSAGE_DIR", filepath.Join(dir, "store"))
+	t.Setenv("PASSAGE_IDENTITIES_FILE", filepath.Join(dir, "identities"))
+
+	if _, err := checkPassageStore(); err == nil || !strings.Contains(err.Error(), "passage init") {
+		t.Errorf("checkPassageStore() error = %v, want no store", err)
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "store"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkPassageStore(); err == nil || !strings.Contains(err.Error(), "no age identities") {
+		t.Errorf("checkPassageStore() error = %v, want no identities", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "identities"), []byte("AGE-SECRET-KEY-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := checkPassageStore(); err != nil || got != filepath.Join(dir, "store") {
+		t.Errorf("checkPassageStore() = %s, %v", got, err)
+	}
+}
+
+func TestClientFields(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// The fake keeps entries as files in the store directory
+	store := filepath.Join(dir, "store")
+	fakeTool(t, dir, "pass", `entry="`+store+`/$(echo "$*" | awk '{print $NF}' | tr / _)"
+case "$1" in
+show) [ -f "$entry" ] || { echo "Error: $2 is not in the password store." >&2; exit 1; }; cat "$entry" ;;
+insert) mkdir -p `+store+`; cat > "$entry" ;;
+esac
+`)
+
+	c, err := New(BackendPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Insert("tailscale/oauth#client_id", "k123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Insert("tailscale/oauth#client_secret", "tskey-client-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Insert("tailscale/oauth#client_id", "k456"); err == nil {
+		t.Error("Insert() of an existing field error = nil")
+	}
+	if err := c.Overwrite("tailscale/oauth#client_id", "k456"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Insert("tailscale/oauth#client_secret", "two\nlines"); err == nil {
+		t.Error("Insert() of a multi-line field error = nil")
+	}
+
+	if got, err := c.Get("tailscale/oauth#client_id"); err != nil || got != "k456" {
+		t.Errorf("Get(client_id) = %q, %v", got, err)
+	}
+	if got, err := c.Get("tailscale/oauth#client_secret"); err != nil || got != "tskey-client-secret" {
+		t.Errorf("Get(client_secret) = %q, %v", got, err)
+	}
+	if _, err := c.Get("tailscale/oauth"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v for the empty password line, want empty", err)
+	}
+}
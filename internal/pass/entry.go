@@ -0,0 +1,53 @@
+package pass
+
+import "strings"
+
+// Entries follow the pass convention of the password on the first line and
+// optional "key: value" fields on the lines below, such as:
+//
+//	tskey-client-secret
+//	client_id: k123
+//	client_secret: tskey-client-secret
+
+// SplitField splits a path such as "tailscale/oauth#client_secret" into the
+// entry name and the field, which is empty for the password
+func SplitField(path string) (name, field string) {
+	name, field, _ = strings.Cut(path, "#")
+	return name, field
+}
+
+// entryPassword returns the first line of an entry
+func entryPassword(content string) string {
+	first, _, _ := strings.Cut(content, "\n")
+	return strings.TrimSpace(first)
+}
+
+// entryField returns the value of a field, matching its key without case
+func entryField(content, field string) (string, bool) {
+	lines := strings.Split(content, "\n")
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), field) {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
+
+// setEntryField sets a field of an entry, replacing the first line with it
+// or adding it at the end, and reports whether it existed. An empty entry
+// gets an empty password line.
+func setEntryField(content, field, value string) (string, bool) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	line := field + ": " + value
+
+	for i := 1; i < len(lines); i++ {
+		key, _, ok := strings.Cut(lines[i], ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), field) {
+			lines[i] = line
+			return strings.Join(lines, "\n") + "\n", true
+		}
+	}
+
+	return strings.Join(append(lines, line), "\n") + "\n", false
+}
@@ -0,0 +1,57 @@
+package pass
+
+import "testing"
+
+const oauthEntry = `tskey-client-secret
+client_id: k123
+Client_Secret : tskey-client-secret
+url: https://login.tailscale.com
+`
+
+func TestSplitField(t *testing.T) {
+	if name, field := SplitField("tailscale/oauth#client_id"); name != "tailscale/oauth" || field != "client_id" {
+		t.Errorf("SplitField() = %s, %s", name, field)
+	}
+	if name, field := SplitField("tailscale/api-key"); name != "tailscale/api-key" || field != "" {
+		t.Errorf("SplitField() = %s, %s", name, field)
+	}
+}
+
+func TestEntryFields(t *testing.T) {
+	if got := entryPassword(oauthEntry); got != "tskey-client-secret" {
+		t.Errorf("entryPassword() = %q", got)
+	}
+
+	tests := []struct {
+		field  string
+		want   string
+		wantOK bool
+	}{
+		{field: "client_id", want: "k123", wantOK: true},
+		{field: "client_secret", want: "tskey-client-secret", wantOK: true},
+		{field: "url", want: "https://login.tailscale.com", wantOK: true},
+		{field: "tskey-client-secret"},
+		{field: "tags"},
+	}
+	for _, tt := range tests {
+		if got, ok := entryField(oauthEntry, tt.field); got != tt.want || ok != tt.wantOK {
+			t.Errorf("entryField(%s) = %q, %v, want %q, %v", tt.field, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestSetEntryField(t *testing.T) {
+	got, existed := setEntryField(oauthEntry, "client_id", "k456")
+	want := "tskey-client-secret\nclient_id: k456\nClient_Secret : tskey-client-secret\nurl: https://login.tailscale.com\n"
+	if got != want || !existed {
+		t.Errorf("setEntryField() = %q, %v, want %q", got, existed, want)
+	}
+
+	got, existed = setEntryField("", "client_id", "k123")
+	if got != "\nclient_id: k123\n" || existed {
+		t.Errorf("setEntryField() on a new entry = %q, %v", got, existed)
+	}
+	if value, _ := entryField(got, "client_id"); value != "k123" {
+		t.Errorf("entryField() after setEntryField() = %q", value)
+	}
+}
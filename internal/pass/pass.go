@@ -2,31 +2,83 @@ package pass
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
-// Client represents a pass password manager client
+// ErrNotFound is returned by Get for an entry that is not in the password
+// store
+var ErrNotFound = errors.New("secret not found")
+
+// Client represents a password store client, for pass or a tool with the
+// same entry paths such as gopass or passage
 type Client struct {
-	passPath string
+	backend *backend
+	binPath string
 }
 
-// New creates a new pass client
-func New() (*Client, error) {
-	// Check if pass is installed
-	passPath, err := exec.LookPath("pass")
+// New creates a client for the named backend, or for BackendAuto or an
+// empty name the first of Backends that is installed
+func New(name string) (*Client, error) {
+	b, binPath, err := lookupBackend(name)
 	if err != nil {
-		return nil, fmt.Errorf("pass not found: please install pass (https://www.passwordstore.org/)")
+		return nil, err
 	}
 
-	return &Client{passPath: passPath}, nil
+	return &Client{backend: b, binPath: binPath}, nil
+}
+
+// Name returns the name of the backend, such as BackendPass
+func (c *Client) Name() string {
+	return c.backend.name
+}
+
+// UsesGPG reports whether the backend decrypts secrets with the GPG agent
+func (c *Client) UsesGPG() bool {
+	return c.backend.usesGPG
 }
 
-// Get retrieves a secret from pass
+// CheckStore checks that the backend's store is initialized, returning its
+// directory
+func (c *Client) CheckStore() (string, error) {
+	return c.backend.checkStore()
+}
+
+// Get retrieves a secret: the password on the first line of the entry, or
+// for a path such as "tailscale/oauth#client_secret" the named field
 func (c *Client) Get(path string) (string, error) {
-	cmd := exec.Command(c.passPath, "show", path)
+	name, field := SplitField(path)
+
+	content, err := c.show(name)
+	if err != nil {
+		return "", err
+	}
+
+	var secret string
+	if field == "" {
+		secret = entryPassword(content)
+	} else {
+		var ok bool
+		if secret, ok = entryField(content, field); !ok {
+			return "", fmt.Errorf("%w: entry '%s' has no field '%s'", ErrNotFound, name, field)
+		}
+	}
+
+	if secret == "" {
+		return "", fmt.Errorf("secret at '%s' is empty", path)
+	}
+
+	return secret, nil
+}
+
+// show returns the whole content of an entry
+func (c *Client) show(name string) (string, error) {
+	cmd := exec.Command(c.binPath, c.backend.showArgs(name)...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -34,33 +86,64 @@ func (c *Client) Get(path string) (string, error) {
 
 	if err := cmd.Run(); err != nil {
 		stderrStr := strings.TrimSpace(stderr.String())
-		if strings.Contains(stderrStr, "is not in the password store") {
-			return "", fmt.Errorf("secret not found at '%s'", path)
+		if strings.Contains(strings.ToLower(stderrStr), c.backend.notFound) {
+			return "", fmt.Errorf("%w at '%s'", ErrNotFound, name)
 		}
-		return "", fmt.Errorf("failed to retrieve secret from pass: %s", stderrStr)
+		return "", fmt.Errorf("failed to retrieve secret from %s: %s", c.backend.name, stderrStr)
 	}
 
-	secret := strings.TrimSpace(stdout.String())
-	if secret == "" {
-		return "", fmt.Errorf("secret at '%s' is empty", path)
+	return stdout.String(), nil
+}
+
+// Insert adds a secret, failing if it exists. For a path with a field, the
+// field is added to the entry, which is created if needed.
+func (c *Client) Insert(path, value string) error {
+	return c.store(path, value, false)
+}
+
+// Overwrite adds a secret, replacing any existing entry, or for a path
+// with a field only that field
+func (c *Client) Overwrite(path, value string) error {
+	return c.store(path, value, true)
+}
+
+// store writes a secret, or sets a field by rewriting its entry
+func (c *Client) store(path, value string, force bool) error {
+	name, field := SplitField(path)
+	if field == "" {
+		return c.insert(name, value, force)
 	}
 
-	return secret, nil
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("cannot store a multi-line secret in field '%s' of '%s'", field, name)
+	}
+
+	content, err := c.show(name)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	content, existed := setEntryField(content, field, value)
+	if existed && !force {
+		return fmt.Errorf("field '%s' already exists in '%s'", field, name)
+	}
+
+	return c.insert(name, content, true)
 }
 
-// Insert adds a secret to pass
-func (c *Client) Insert(path, value string) error {
-	cmd := exec.Command(c.passPath, "insert", "-m", path)
+// insert writes a whole entry
+func (c *Client) insert(name, content string, force bool) error {
+	cmd := exec.Command(c.binPath, c.backend.insertArgs(name, force)...)
 
 	var stdin bytes.Buffer
-	stdin.WriteString(value)
+	stdin.WriteString(content)
 	cmd.Stdin = &stdin
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to insert secret into pass: %s", stderr.String())
+		return fmt.Errorf("failed to insert secret into %s: %s", c.backend.name, stderr.String())
 	}
 
 	return nil
@@ -72,31 +155,75 @@ func (c *Client) Exists(path string) bool {
 	return err == nil
 }
 
-// IsInstalled checks if pass is installed and available
-func IsInstalled() bool {
-	_, err := exec.LookPath("pass")
-	return err == nil
-}
+// ValidatePath checks that path names a whole entry inside the password
+// store, not a field of one
+func ValidatePath(path string) error {
+	if strings.Contains(path, "#") {
+		return fmt.Errorf("invalid pass path '%s': must name a whole entry, not a field", path)
+	}
 
-// GetFromPassOrEnv attempts to get a value from pass first, then falls back to environment variable
-func GetFromPassOrEnv(passClient *Client, passPath, envVar string) (string, error) {
-	// Try pass first if client is available
-	if passClient != nil {
-		value, err := passClient.Get(passPath)
-		if err == nil {
-			return value, nil
+	if path == "" || strings.HasPrefix(path, "/") || strings.HasSuffix(path, "/") {
+		return fmt.Errorf("invalid pass path '%s': must be a relative entry name like 'tailscale/authkey'", path)
+	}
+
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return fmt.Errorf("invalid pass path '%s': must not contain empty, '.' or '..' elements", path)
 		}
-		// If pass fails for any reason other than "not found", we might want to log it
 	}
 
-	// Fall back to environment variable
-	value := os.Getenv(envVar)
-	if value == "" {
-		if passClient != nil {
-			return "", fmt.Errorf("secret not found in pass at '%s' or environment variable '%s'", passPath, envVar)
+	return nil
+}
+
+// StoreDir returns the password store directory, PASSWORD_STORE_DIR or
+// ~/.password-store
+func StoreDir() (string, error) {
+	if dir := os.Getenv("PASSWORD_STORE_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".password-store"), nil
+}
+
+// CheckStore checks that the password store exists and is initialized with
+// a GPG key
+func CheckStore() (string, error) {
+	dir, err := StoreDir()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ".gpg-id"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return dir, fmt.Errorf("password store at %s is not initialized: run 'pass init <gpg-id>'", dir)
 		}
-		return "", fmt.Errorf("pass not available and environment variable '%s' not set", envVar)
+		return dir, fmt.Errorf("failed to read password store: %w", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return dir, fmt.Errorf("password store at %s has no GPG key in .gpg-id", dir)
 	}
 
-	return value, nil
+	return dir, nil
+}
+
+// CheckGPGAgent checks that the GPG agent, which pass uses to decrypt
+// secrets, responds
+func CheckGPGAgent(ctx context.Context) error {
+	agentPath, err := exec.LookPath("gpg-connect-agent")
+	if err != nil {
+		return fmt.Errorf("gpg-connect-agent not found: is GnuPG installed?")
+	}
+
+	out, err := exec.CommandContext(ctx, agentPath, "/bye").CombinedOutput()
+	if err != nil {
+		// The last line has the reason, after progress messages
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		return fmt.Errorf("GPG agent does not respond: %s", lines[len(lines)-1])
+	}
+	return nil
 }
@@ -0,0 +1,48 @@
+package pass
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		path      string
+		wantError bool
+	}{
+		{path: "tailscale/authkey"},
+		{path: "authkey"},
+		{path: "", wantError: true},
+		{path: "/tailscale/authkey", wantError: true},
+		{path: "tailscale/", wantError: true},
+		{path: "tailscale//authkey", wantError: true},
+		{path: "tailscale/../authkey", wantError: true},
+		{path: "tailscale/oauth#client_secret", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if err := ValidatePath(tt.path); (err != nil) != tt.wantError {
+				t.Errorf("ValidatePath(%q) error = %v, wantError %v", tt.path, err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestCheckStore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PASSWORD_STORE_DIR", dir)
+
+	if _, err := CheckStore(); err == nil || !strings.Contains(err.Error(), "not initialized") {
+		t.Errorf("CheckStore() error = %v, want not initialized", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".gpg-id"), []byte("me@example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := CheckStore(); err != nil || got != dir {
+		t.Errorf("CheckStore() = %s, %v, want %s", got, err, dir)
+	}
+}
@@ -0,0 +1,128 @@
+// Package redact masks secrets in values that are logged or displayed.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces a redacted value
+const Mask = "****"
+
+// sensitiveFields are JSON and form field names whose values are secrets
+var sensitiveFields = map[string]bool{
+	"key":           true,
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"client_secret": true,
+	"api_key":       true,
+	"apikey":        true,
+	"secret":        true,
+	"password":      true,
+	"token":         true,
+	"authorization": true,
+}
+
+// tailscaleKeyPattern matches Tailscale API keys, auth keys and OAuth secrets
+var tailscaleKeyPattern = regexp.MustCompile(`tskey-([a-z]+)-[A-Za-z0-9_-]+`)
+
+// IsSensitiveField reports whether a field name holds a secret
+func IsSensitiveField(name string) bool {
+	return sensitiveFields[strings.ToLower(name)]
+}
+
+// Secret masks a secret entirely, keeping only a Tailscale key type prefix
+// such as "tskey-auth-" so the kind of secret remains recognizable
+func Secret(s string) string {
+	if m := tailscaleKeyPattern.FindStringSubmatch(s); m != nil && m[0] == s {
+		return "tskey-" + m[1] + "-" + Mask
+	}
+	return Mask
+}
+
+// Partial masks the middle of an identifier that is not secret on its own,
+// such as an OAuth client ID, keeping a few characters for recognition
+func Partial(s string) string {
+	if len(s) <= 8 {
+		return Mask
+	}
+	return s[:4] + Mask + s[len(s)-4:]
+}
+
+// Text masks Tailscale keys appearing anywhere in free-form text
+func Text(s string) string {
+	return tailscaleKeyPattern.ReplaceAllStringFunc(s, Secret)
+}
+
+// JSON masks the values of sensitive fields in a JSON document. Data that
+// is not valid JSON is masked as text.
+func JSON(data []byte) []byte {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return []byte(Text(string(data)))
+	}
+
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return []byte(Text(string(data)))
+	}
+	return out
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if s, ok := val.(string); ok && IsSensitiveField(k) {
+				v[k] = Secret(s)
+				continue
+			}
+			v[k] = redactValue(val)
+		}
+		return v
+	case []any:
+		for i, val := range v {
+			v[i] = redactValue(val)
+		}
+		return v
+	case string:
+		return Text(v)
+	}
+	return v
+}
+
+// Form returns a copy of form values with sensitive fields masked
+func Form(values url.Values) url.Values {
+	out := make(url.Values, len(values))
+	for k, vals := range values {
+		masked := make([]string, len(vals))
+		for i, v := range vals {
+			if IsSensitiveField(k) {
+				masked[i] = Secret(v)
+			} else {
+				masked[i] = Text(v)
+			}
+		}
+		out[k] = masked
+	}
+	return out
+}
+
+// Header returns a copy of HTTP headers with credentials masked
+func Header(h http.Header) http.Header {
+	out := h.Clone()
+	for k := range out {
+		switch http.CanonicalHeaderKey(k) {
+		case "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie":
+			out[k] = []string{Mask}
+		}
+	}
+	return out
+}
@@ -0,0 +1,254 @@
+// Package retry implements the retry policy shared by the Tailscale API clients.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the default number of retries after the first attempt
+	DefaultMaxRetries = 3
+
+	// DefaultBaseDelay is the backoff delay before the first retry
+	DefaultBaseDelay = time.Second
+
+	// DefaultMaxDelay caps the backoff delay between two attempts
+	DefaultMaxDelay = 10 * time.Second
+
+	// DefaultBudget is the default total time spent waiting between attempts
+	DefaultBudget = 30 * time.Second
+)
+
+// Policy controls when and how often failed requests are retried
+type Policy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+
+	// BaseDelay is the backoff delay before the first retry, doubled for
+	// every subsequent retry
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff delay between two attempts. It does not
+	// apply to delays requested by the server with Retry-After.
+	MaxDelay time.Duration
+
+	// Budget is the total time that may be spent waiting between attempts.
+	// A retry whose delay would exceed the remaining budget is not attempted.
+	Budget time.Duration
+}
+
+// DefaultPolicy returns the default retry policy
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultBaseDelay,
+		MaxDelay:   DefaultMaxDelay,
+		Budget:     DefaultBudget,
+	}
+}
+
+// Attempt describes a retry that is about to be made
+type Attempt struct {
+	// Number of the retry, starting at 1
+	Number int
+
+	// MaxRetries allowed by the policy
+	MaxRetries int
+
+	// Wait is the delay before the retry
+	Wait time.Duration
+
+	// Reason the previous attempt failed
+	Reason error
+}
+
+// Do executes the request built by newRequest, retrying retryable failures
+// according to the policy. A fresh request is built for every attempt so
+// request bodies can be resent; newRequest should bind ctx to the request.
+// If retries are exhausted on a retryable status code, the last response is
+// returned for the caller to handle. onRetry, if not nil, is called before
+// every retry. Waiting between attempts is interrupted when ctx is done.
+func (p Policy) Do(ctx context.Context, client *http.Client, newRequest func(ctx context.Context) (*http.Request, error), onRetry func(Attempt)) (*http.Response, error) {
+	var waited time.Duration
+
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest(context.WithValue(ctx, attemptKey{}, attempt+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := client.Do(req)
+
+		var reason error
+		var retryAfter time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || !IsRetryableError(req.Method, err) {
+				return nil, err
+			}
+			reason = err
+		case IsRetryableStatus(req.Method, resp.StatusCode):
+			reason = fmt.Errorf("%s", resp.Status)
+			retryAfter = ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		default:
+			return resp, nil
+		}
+
+		wait := p.backoff(attempt + 1)
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+
+		if attempt >= p.MaxRetries || waited+wait > p.Budget {
+			if resp != nil {
+				return resp, nil
+			}
+			if attempt == 0 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed after %d retries: %w", attempt, err)
+		}
+
+		if resp != nil {
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if onRetry != nil {
+			onRetry(Attempt{
+				Number:     attempt + 1,
+				MaxRetries: p.MaxRetries,
+				Wait:       wait,
+				Reason:     reason,
+			})
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		waited += wait
+	}
+}
+
+type attemptKey struct{}
+
+// AttemptNumber returns the attempt number, starting at 1, of the request
+// whose context is ctx, or 0 if the request was not made by Do
+func AttemptNumber(ctx context.Context) int {
+	n, _ := ctx.Value(attemptKey{}).(int)
+	return n
+}
+
+// backoff returns the jittered exponential backoff delay before a retry
+func (p Policy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << (retry - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	// Equal jitter: half the delay is fixed, the other half random
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// IsIdempotent reports whether requests with the given method can safely
+// be repeated if their outcome is unknown
+func IsIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// IsRetryableStatus reports whether a response status code is worth
+// retrying. 429 and 503 mean the server did not process the request, so
+// they are retried for any method; other gateway and server errors are
+// only retried for idempotent methods.
+func IsRetryableStatus(method string, statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return IsIdempotent(method)
+	}
+	return false
+}
+
+// IsRetryableError reports whether a transport error is worth retrying.
+// Errors that occur before the request was sent are always retryable;
+// errors that leave the outcome unknown are only retried for idempotent
+// methods.
+func IsRetryableError(method string, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// The request never left this machine
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	// The request may have been processed
+	if !IsIdempotent(method) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// ParseRetryAfter parses a Retry-After header value given either as a number
+// of seconds or as an HTTP date. It returns 0 if the value is missing or
+// invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,235 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func testPolicy() Policy {
+	return Policy{
+		MaxRetries: 3,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   5 * time.Millisecond,
+		Budget:     time.Second,
+	}
+}
+
+// statusServer responds with the given status codes in order, then 200
+func statusServer(t *testing.T, headers http.Header, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			for k, v := range headers {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func doRequest(t *testing.T, p Policy, method, url string) (*http.Response, error) {
+	t.Helper()
+
+	return p.Do(context.Background(), http.DefaultClient, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, method, url, nil)
+	}, nil)
+}
+
+func TestDoRetriesStatusCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statuses   []int
+		wantStatus int
+		wantCalls  int32
+	}{
+		{"429 is retried for POST", http.MethodPost, []int{429, 429}, 200, 3},
+		{"503 is retried for POST", http.MethodPost, []int{503}, 200, 2},
+		{"500 is retried for GET", http.MethodGet, []int{500, 502, 504}, 200, 4},
+		{"500 is not retried for POST", http.MethodPost, []int{500}, 500, 1},
+		{"400 is not retried", http.MethodGet, []int{400}, 400, 1},
+		{"retries are exhausted", http.MethodGet, []int{503, 503, 503, 503, 503}, 503, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := statusServer(t, nil, tt.statuses...)
+
+			resp, err := doRequest(t, testPolicy(), tt.method, server.URL)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls.Load(), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoRetryAfterExceedsBudget(t *testing.T) {
+	server, calls := statusServer(t, http.Header{"Retry-After": {"60"}}, 429)
+
+	start := time.Now()
+	resp, err := doRequest(t, testPolicy(), http.MethodGet, server.URL)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 {
+		t.Errorf("status = %d after %d calls, want 429 after 1 call", resp.StatusCode, calls.Load())
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("Do() waited despite Retry-After exceeding the budget")
+	}
+}
+
+func TestDoReportsRetries(t *testing.T) {
+	server, _ := statusServer(t, http.Header{"Retry-After": {"0"}}, 429)
+
+	var attempts []Attempt
+	resp, err := testPolicy().Do(context.Background(), http.DefaultClient, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	}, func(a Attempt) {
+		attempts = append(attempts, a)
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if len(attempts) != 1 || attempts[0].Number != 1 || attempts[0].MaxRetries != 3 {
+		t.Errorf("attempts = %+v, want one retry", attempts)
+	}
+}
+
+func TestDoConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + listener.Addr().String()
+	listener.Close()
+
+	var retries int
+	_, err = testPolicy().Do(context.Background(), http.DefaultClient, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	}, func(Attempt) { retries++ })
+
+	if err == nil {
+		t.Fatal("Do() error = nil, want connection refused")
+	}
+	if retries != 3 {
+		t.Errorf("retries = %d, want 3", retries)
+	}
+}
+
+func TestDoStopsWhenContextIsDone(t *testing.T) {
+	server, calls := statusServer(t, http.Header{"Retry-After": {"20"}}, 429)
+
+	p := testPolicy()
+	p.Budget = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.Do(ctx, http.DefaultClient, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	}, nil)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() error = %v, want context.DeadlineExceeded", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Do() did not stop waiting when the context was done")
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	tests := []struct {
+		name   string
+		method string
+		err    error
+		want   bool
+	}{
+		{"dial error for POST", http.MethodPost, refused, true},
+		{"reset for GET", http.MethodGet, reset, true},
+		{"reset for POST", http.MethodPost, reset, false},
+		{"unexpected EOF for DELETE", http.MethodDelete, fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"temporary DNS failure", http.MethodPost, &net.DNSError{IsTemporary: true}, true},
+		{"unknown host", http.MethodGet, &net.DNSError{IsNotFound: true}, false},
+		{"other error", http.MethodGet, errors.New("timeout"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableError(tt.method, tt.err); got != tt.want {
+				t.Errorf("IsRetryableError(%s, %v) = %v, want %v", tt.method, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := ParseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("ParseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffIsBounded(t *testing.T) {
+	p := Policy{BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+
+	for retry := 1; retry <= 10; retry++ {
+		d := p.backoff(retry)
+		if d < 0 || d > p.MaxDelay {
+			t.Errorf("backoff(%d) = %v, want between 0 and %v", retry, d, p.MaxDelay)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package tailscale
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Authenticator attaches credentials to outgoing Tailscale API requests
+type Authenticator interface {
+	// Authenticate sets the credentials on the request. Implementations that
+	// need to fetch credentials should honor the request context.
+	Authenticate(req *http.Request) error
+
+	// Name returns a human-readable name for the credential, used in errors
+	Name() string
+
+	// Help returns additional guidance for an API error status code
+	Help(statusCode int) string
+}
+
+// APIKey authenticates requests with a Tailscale API key using basic auth
+type APIKey string
+
+// Authenticate sets the API key as the basic auth username
+func (k APIKey) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(string(k), "")
+	return nil
+}
+
+// Name returns the credential name
+func (k APIKey) Name() string {
+	return "API key"
+}
+
+// Help returns guidance for API key errors
+func (k APIKey) Help(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return "API keys expire after 90 days. Generate a new one at:\nhttps://login.tailscale.com/admin/settings/keys"
+	case http.StatusForbidden:
+		return "Ensure your API key has the required permissions"
+	}
+	return ""
+}
+
+// BearerToken authenticates requests with an OAuth access token
+type BearerToken string
+
+// Authenticate sets the access token as a bearer token
+func (t BearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// Name returns the credential name
+func (t BearerToken) Name() string {
+	return "OAuth access token"
+}
+
+// Help returns guidance for OAuth access token errors
+func (t BearerToken) Help(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return "The OAuth access token may have expired or is invalid"
+	case http.StatusForbidden:
+		return "Ensure your OAuth client has the required permissions"
+	}
+	return ""
+}
+
+// ValidateAPIKey validates an API key by checking its format
+// Note: We don't make a test API call because there's no good validation endpoint
+// The key will be validated when we try to create an auth key
+func ValidateAPIKey(apiKey string) error {
+	// Basic format check - Tailscale API keys start with "tskey-api-"
+	if len(apiKey) < 10 || !strings.HasPrefix(apiKey, "tskey-api-") {
+		return fmt.Errorf("API key appears to be invalid (should start with 'tskey-api-')\n\nGenerate a new one at: https://login.tailscale.com/admin/settings/keys")
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package tailscale
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ironicbadger/jankey/internal/logging"
+	"github.com/ironicbadger/jankey/internal/retry"
+)
+
+const (
+	// DefaultBaseURL is the base URL of the public Tailscale API
+	DefaultBaseURL = "https://api.tailscale.com"
+
+	// DefaultTailnet refers to the tailnet that owns the credential
+	DefaultTailnet = "-"
+)
+
+// Client represents a Tailscale API client
+type Client struct {
+	auth       Authenticator
+	baseURL    string
+	tailnet    string
+	retry      retry.Policy
+	httpClient *http.Client
+	log        *slog.Logger
+}
+
+// Options configures a Tailscale API client
+type Options struct {
+	// BaseURL of the API, defaults to DefaultBaseURL
+	BaseURL string
+
+	// Tailnet to operate on, defaults to DefaultTailnet
+	Tailnet string
+
+	// Retry policy for failed requests, defaults to retry.DefaultPolicy
+	Retry retry.Policy
+
+	// Logger for diagnostics, nil discards them
+	Logger *slog.Logger
+}
+
+// New creates a new Tailscale API client using the given authenticator
+func New(auth Authenticator, opts Options) *Client {
+	tailnet := opts.Tailnet
+	if tailnet == "" {
+		tailnet = DefaultTailnet
+	}
+
+	retryPolicy := opts.Retry
+	if retryPolicy == (retry.Policy{}) {
+		retryPolicy = retry.DefaultPolicy()
+	}
+
+	log := logging.OrDiscard(opts.Logger)
+
+	return &Client{
+		auth:    auth,
+		baseURL: NormalizeBaseURL(opts.BaseURL),
+		tailnet: tailnet,
+		retry:   retryPolicy,
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &logging.Transport{Logger: log},
+		},
+		log: log,
+	}
+}
+
+// NormalizeBaseURL returns the base URL without a trailing slash, falling
+// back to DefaultBaseURL when empty
+func NormalizeBaseURL(baseURL string) string {
+	if baseURL == "" {
+		return DefaultBaseURL
+	}
+	return strings.TrimRight(baseURL, "/")
+}
+
+// ValidateBaseURL checks that a base URL is an absolute http(s) URL
+func ValidateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid API base URL '%s': %w", baseURL, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid API base URL '%s': must be an absolute http or https URL", baseURL)
+	}
+
+	return nil
+}
+
+// tailnetURL returns the URL of a resource under the client's tailnet
+func (c *Client) tailnetURL(elem ...string) string {
+	parts := []string{c.baseURL, "api/v2/tailnet", url.PathEscape(c.tailnet)}
+	for _, e := range elem {
+		parts = append(parts, url.PathEscape(e))
+	}
+	return strings.Join(parts, "/")
+}
+
+// do builds, authenticates and executes an API request, returning the
+// response status code and body
+func (c *Client) do(ctx context.Context, method, reqURL string, body []byte) (int, []byte, error) {
+	resp, err := c.retry.Do(ctx, c.httpClient, func(ctx context.Context) (*http.Request, error) {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
+		if err != nil {
+			return nil, err
+		}
+
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json")
+
+		if err := c.auth.Authenticate(req); err != nil {
+			return nil, fmt.Errorf("failed to authenticate request: %w", err)
+		}
+
+		return req, nil
+	}, logging.RetryLogger(ctx, c.log))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return resp.StatusCode, respBody, nil
+}
+
+// startRequest assigns a request ID to a logical API request, returning a
+// context and logger carrying it
+func (c *Client) startRequest(ctx context.Context) (context.Context, *slog.Logger) {
+	id := logging.NewRequestID()
+	return logging.WithRequestID(ctx, id), c.log.With("request_id", id)
+}
+
+// APIError is an error response from the Tailscale API
+type APIError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the HTTP status code of an APIError in err's chain,
+// or 0
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// handleAPIError formats Tailscale API errors as an APIError
+func (c *Client) handleAPIError(statusCode int, body []byte) error {
+	return &APIError{StatusCode: statusCode, Err: c.formatAPIError(statusCode, body)}
+}
+
+// formatAPIError describes an API error response, with guidance for the
+// credential in use
+func (c *Client) formatAPIError(statusCode int, body []byte) error {
+	var errorMsg string
+
+	// Try to parse error response
+	var errorResp struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+
+	if err := json.Unmarshal(body, &errorResp); err == nil {
+		if errorResp.Message != "" {
+			errorMsg = errorResp.Message
+		} else if errorResp.Error != "" {
+			errorMsg = errorResp.Error
+		}
+	}
+
+	if errorMsg == "" {
+		errorMsg = string(body)
+	}
+
+	var err error
+	switch statusCode {
+	case http.StatusUnauthorized:
+		err = fmt.Errorf("%s invalid or expired (401): %s", c.auth.Name(), errorMsg)
+	case http.StatusForbidden:
+		err = fmt.Errorf("access forbidden (403): %s", errorMsg)
+	case http.StatusBadRequest:
+		if strings.Contains(errorMsg, "capability") {
+			return fmt.Errorf("invalid request (400): %s\n\nThis may be due to missing or invalid tags in the request", errorMsg)
+		}
+		return fmt.Errorf("invalid request (400): %s", errorMsg)
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("rate limited (429): %s\n\nPlease wait before retrying", errorMsg)
+	default:
+		return fmt.Errorf("API request failed (%d): %s", statusCode, errorMsg)
+	}
+
+	if help := c.auth.Help(statusCode); help != "" {
+		return fmt.Errorf("%w\n\n%s", err, help)
+	}
+	return err
+}
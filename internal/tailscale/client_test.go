@@ -0,0 +1,89 @@
+package tailscale
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientUsesBaseURLAndTailnet(t *testing.T) {
+	tests := []struct {
+		name     string
+		auth     Authenticator
+		tailnet  string
+		wantPath string
+		checkReq func(t *testing.T, r *http.Request)
+	}{
+		{
+			name:     "API key with default tailnet",
+			auth:     APIKey("tskey-api-test"),
+			wantPath: "/api/v2/tailnet/-/keys",
+			checkReq: func(t *testing.T, r *http.Request) {
+				user, _, ok := r.BasicAuth()
+				if !ok || user != "tskey-api-test" {
+					t.Errorf("basic auth user = %q, want %q", user, "tskey-api-test")
+				}
+			},
+		},
+		{
+			name:     "bearer token with named tailnet",
+			auth:     BearerToken("token"),
+			tailnet:  "example.com",
+			wantPath: "/api/v2/tailnet/example.com/keys",
+			checkReq: func(t *testing.T, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "Bearer token" {
+					t.Errorf("Authorization = %q, want %q", got, "Bearer token")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.wantPath {
+					t.Errorf("path = %q, want %q", r.URL.Path, tt.wantPath)
+				}
+				tt.checkReq(t, r)
+				json.NewEncoder(w).Encode(map[string]any{
+					"keys": []map[string]string{{"id": "k1", "description": "Generated by jankey"}},
+				})
+			}))
+			defer server.Close()
+
+			client := New(tt.auth, Options{BaseURL: server.URL + "/", Tailnet: tt.tailnet})
+			keys, err := client.ListAuthKeys(context.Background())
+			if err != nil {
+				t.Fatalf("ListAuthKeys() error = %v", err)
+			}
+
+			if len(keys) != 1 || keys[0].ID != "k1" {
+				t.Errorf("ListAuthKeys() = %+v, want one key with ID k1", keys)
+			}
+		})
+	}
+}
+
+func TestValidateBaseURL(t *testing.T) {
+	tests := []struct {
+		baseURL   string
+		wantError bool
+	}{
+		{"https://api.tailscale.com", false},
+		{"http://127.0.0.1:8080", false},
+		{"api.tailscale.com", true},
+		{"ftp://example.com", true},
+		{"https://", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseURL, func(t *testing.T) {
+			err := ValidateBaseURL(tt.baseURL)
+			if (err != nil) != tt.wantError {
+				t.Errorf("ValidateBaseURL(%q) error = %v, wantError %v", tt.baseURL, err, tt.wantError)
+			}
+		})
+	}
+}
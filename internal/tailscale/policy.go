@@ -0,0 +1,36 @@
+package tailscale
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// TagOwners returns the tagOwners section of the tailnet policy file,
+// mapping each defined tag to its owners
+func (c *Client) TagOwners(ctx context.Context) (map[string][]string, error) {
+	ctx, log := c.startRequest(ctx)
+	log.Debug("fetching policy file")
+
+	statusCode, body, err := c.do(ctx, http.MethodGet, c.tailnetURL("acl"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, c.handleAPIError(statusCode, body)
+	}
+
+	var policy struct {
+		TagOwners map[string][]string `json:"tagOwners"`
+	}
+
+	if err := json.Unmarshal(body, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+
+	log.Debug("fetched policy file", "tags", len(policy.TagOwners))
+
+	return policy.TagOwners, nil
+}
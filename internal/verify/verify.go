@@ -0,0 +1,244 @@
+// Package verify checks that Tailscale credentials work and can create the
+// configured auth keys, before they are saved or used.
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ironicbadger/jankey/internal/oauth"
+	"github.com/ironicbadger/jankey/internal/tailscale"
+)
+
+// Kinds of problems found by Verify
+const (
+	// ProblemCredential is a missing, malformed or rejected credential
+	ProblemCredential = "credential"
+
+	// ProblemScope is a credential lacking the permissions to manage auth
+	// keys
+	ProblemScope = "scope"
+
+	// ProblemTailnet is a tailnet that does not exist or is not accessible
+	ProblemTailnet = "tailnet"
+
+	// ProblemTag is a tag not defined in the tailnet policy's tagOwners
+	ProblemTag = "tag"
+
+	// ProblemPolicy is a policy file that could not be read to check tags
+	ProblemPolicy = "policy"
+
+	// ProblemAPI is any other API or network failure
+	ProblemAPI = "api"
+)
+
+// Options configure Verify. Exactly one of APIKey or ClientID and
+// ClientSecret should be set.
+type Options struct {
+	// API holds the base URL, tailnet, retry policy and logger
+	API tailscale.Options
+
+	APIKey       string
+	ClientID     string
+	ClientSecret string
+
+	// Tags to check against the policy file's tagOwners
+	Tags []string
+}
+
+// Problem is an issue found by Verify
+type Problem struct {
+	// Kind is one of the Problem constants
+	Kind string
+
+	// Tag is the tag concerned, for ProblemTag
+	Tag string
+
+	Message string
+}
+
+// Result is the outcome of Verify
+type Result struct {
+	// Credential is the kind of credential verified
+	Credential string
+
+	// Tailnet is the tailnet addressed, tailscale.DefaultTailnet for the
+	// tailnet of the credential
+	Tailnet string
+
+	// Authenticated reports whether the credential was accepted
+	Authenticated bool
+
+	// Listed reports whether the tailnet's auth keys could be listed
+	Listed bool
+
+	// AuthKeys is the number of auth keys in the tailnet, if Listed
+	AuthKeys int
+
+	// Scopes granted to an OAuth client
+	Scopes []string
+
+	// TagOwners are the owners of each checked tag defined in the policy
+	TagOwners map[string][]string
+
+	Problems []Problem
+}
+
+// OK reports whether no problems were found that prevent creating keys. A
+// policy file that could not be read is only a warning.
+func (r *Result) OK() bool {
+	for _, p := range r.Problems {
+		if p.Kind != ProblemPolicy {
+			return false
+		}
+	}
+	return true
+}
+
+// Has reports whether a problem of the given kind was found
+func (r *Result) Has(kind string) bool {
+	for _, p := range r.Problems {
+		if p.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Result) add(kind, tag, format string, args ...any) {
+	r.Problems = append(r.Problems, Problem{Kind: kind, Tag: tag, Message: fmt.Sprintf(format, args...)})
+}
+
+// Verify authenticates with the credentials, lists the tailnet's auth keys
+// and checks the tags against the policy file. It stops at the first
+// problem that prevents further checks.
+func Verify(ctx context.Context, opts Options) *Result {
+	result := &Result{Tailnet: opts.API.Tailnet}
+	if result.Tailnet == "" {
+		result.Tailnet = tailscale.DefaultTailnet
+	}
+
+	auth, ok := authenticate(ctx, opts, result)
+	if !ok {
+		return result
+	}
+
+	client := tailscale.New(auth, opts.API)
+
+	// Any response other than 401 means the API accepted the credential
+	keys, err := client.ListAuthKeys(ctx)
+	if status := tailscale.StatusCode(err); err == nil || (status != 0 && status != http.StatusUnauthorized) {
+		result.Authenticated = true
+	}
+	if err != nil {
+		switch tailscale.StatusCode(err) {
+		case http.StatusUnauthorized:
+			result.add(ProblemCredential, "", "the %s was rejected: %v", result.Credential, err)
+		case http.StatusForbidden:
+			result.add(ProblemScope, "", "the %s may not manage auth keys: %v", result.Credential, err)
+		case http.StatusNotFound:
+			result.add(ProblemTailnet, "", "tailnet '%s' was not found or is not accessible with this %s", result.Tailnet, result.Credential)
+		default:
+			result.add(ProblemAPI, "", "failed to list auth keys: %v", err)
+		}
+		return result
+	}
+	result.Listed = true
+	result.AuthKeys = len(keys)
+
+	if len(opts.Tags) > 0 {
+		checkTags(ctx, client, opts.Tags, result)
+	}
+
+	return result
+}
+
+// authenticate returns an authenticator for the credentials, exchanging
+// OAuth client credentials for an access token
+func authenticate(ctx context.Context, opts Options, result *Result) (tailscale.Authenticator, bool) {
+	if opts.ClientID == "" && opts.ClientSecret == "" {
+		result.Credential = tailscale.APIKey("").Name()
+
+		if err := tailscale.ValidateAPIKey(opts.APIKey); err != nil {
+			result.add(ProblemCredential, "", "%v", err)
+			return nil, false
+		}
+		return tailscale.APIKey(opts.APIKey), true
+	}
+
+	result.Credential = "OAuth client"
+
+	if opts.ClientID == "" || opts.ClientSecret == "" {
+		result.add(ProblemCredential, "", "both the OAuth client ID and secret are required")
+		return nil, false
+	}
+
+	client := oauth.New(opts.ClientID, opts.ClientSecret, oauth.Options{
+		BaseURL: opts.API.BaseURL,
+		Retry:   opts.API.Retry,
+		Logger:  opts.API.Logger,
+	})
+
+	token, err := client.Token(ctx)
+	if err != nil {
+		switch tailscale.StatusCode(err) {
+		case http.StatusBadRequest, http.StatusUnauthorized:
+			result.add(ProblemCredential, "", "the OAuth client was rejected: %v", err)
+		case http.StatusForbidden:
+			result.add(ProblemScope, "", "the OAuth client may not request a token: %v", err)
+		default:
+			result.add(ProblemAPI, "", "failed to get an OAuth access token: %v", err)
+		}
+		return nil, false
+	}
+
+	result.Authenticated = true
+
+	// An empty scope is not reported by all servers, so only a scope list
+	// without an auth key scope is a problem
+	result.Scopes = strings.Fields(token.Scope)
+	if len(result.Scopes) > 0 && !oauth.CanCreateAuthKeys(token.Scope) {
+		result.add(ProblemScope, "", "the OAuth client has scopes %s but needs one of %s to create auth keys",
+			strings.Join(result.Scopes, ", "), strings.Join(oauth.AuthKeyScopes, ", "))
+	}
+
+	return tailscale.BearerToken(token.AccessToken), true
+}
+
+// checkTags reports tags that the policy file's tagOwners does not define
+func checkTags(ctx context.Context, client *tailscale.Client, tags []string, result *Result) {
+	owners, err := client.TagOwners(ctx)
+	if err != nil {
+		if tailscale.StatusCode(err) == http.StatusForbidden {
+			result.add(ProblemPolicy, "", "cannot read the tailnet policy file to check tag owners; an OAuth client needs the policy_file:read scope")
+		} else {
+			result.add(ProblemPolicy, "", "cannot read the tailnet policy file to check tag owners: %v", err)
+		}
+		return
+	}
+
+	result.TagOwners = make(map[string][]string)
+	for _, tag := range tags {
+		tagOwners, ok := owners[tag]
+		if !ok {
+			result.add(ProblemTag, tag, "%s is not defined in the tagOwners section of the tailnet policy file", tag)
+			continue
+		}
+		result.TagOwners[tag] = tagOwners
+	}
+}
+
+// Tags returns the tags of ProblemTag problems, sorted
+func (r *Result) Tags() []string {
+	var tags []string
+	for _, p := range r.Problems {
+		if p.Kind == ProblemTag {
+			tags = append(tags, p.Tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
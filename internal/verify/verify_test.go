@@ -0,0 +1,137 @@
+package verify
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ironicbadger/jankey/internal/fakeapi"
+	"github.com/ironicbadger/jankey/internal/tailscale"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    fakeapi.Config
+		opts      Options
+		wantKinds string
+		want      string
+		listed    bool
+	}{
+		{
+			name:   "api key",
+			opts:   Options{APIKey: "tskey-api-test", Tags: []string{"tag:ci"}},
+			listed: true,
+		},
+		{
+			name:   "oauth",
+			config: fakeapi.Config{OAuthScope: "auth_keys policy_file:read"},
+			opts:   Options{ClientID: "client-id", ClientSecret: "client-secret", Tags: []string{"tag:ci"}},
+			listed: true,
+		},
+		{
+			name:      "oauth without policy scope",
+			opts:      Options{ClientID: "client-id", ClientSecret: "client-secret", Tags: []string{"tag:ci"}},
+			wantKinds: ProblemPolicy,
+			want:      "policy_file:read scope",
+			listed:    true,
+		},
+		{
+			name:      "malformed api key",
+			opts:      Options{APIKey: "nope"},
+			wantKinds: ProblemCredential,
+		},
+		{
+			name:      "rejected api key",
+			opts:      Options{APIKey: "tskey-api-other"},
+			wantKinds: ProblemCredential,
+			want:      "API key was rejected",
+		},
+		{
+			name:      "rejected oauth client",
+			opts:      Options{ClientID: "client-id", ClientSecret: "wrong"},
+			wantKinds: ProblemCredential,
+			want:      "OAuth client was rejected",
+		},
+		{
+			name:      "missing oauth secret",
+			opts:      Options{ClientID: "client-id"},
+			wantKinds: ProblemCredential,
+		},
+		{
+			name:      "oauth scope",
+			config:    fakeapi.Config{OAuthScope: "devices:read dns"},
+			opts:      Options{ClientID: "client-id", ClientSecret: "client-secret"},
+			wantKinds: ProblemScope,
+			want:      "has scopes devices:read, dns",
+			listed:    true,
+		},
+		{
+			name:      "unknown tailnet",
+			opts:      Options{APIKey: "tskey-api-test", API: tailscale.Options{Tailnet: "other.com"}},
+			wantKinds: ProblemTailnet,
+			want:      "tailnet 'other.com'",
+		},
+		{
+			name:      "unowned tags",
+			opts:      Options{APIKey: "tskey-api-test", Tags: []string{"tag:web", "tag:ci", "tag:db"}},
+			wantKinds: ProblemTag + " " + ProblemTag,
+			want:      "tag:web is not defined",
+			listed:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.APIKeys = []string{"tskey-api-test"}
+			cfg.OAuthClients = map[string]string{"client-id": "client-secret"}
+			cfg.Tailnet = "example.com"
+			cfg.AllowedTags = []string{"tag:ci"}
+
+			server := httptest.NewServer(fakeapi.New(cfg))
+			t.Cleanup(server.Close)
+
+			opts := tt.opts
+			opts.API.BaseURL = server.URL
+
+			result := Verify(context.Background(), opts)
+
+			var kinds []string
+			var messages []string
+			for _, p := range result.Problems {
+				kinds = append(kinds, p.Kind)
+				messages = append(messages, p.Message)
+			}
+			if got := strings.Join(kinds, " "); got != tt.wantKinds {
+				t.Errorf("problems = %q, want kinds %q", messages, tt.wantKinds)
+			}
+			if tt.want != "" && !strings.Contains(strings.Join(messages, "\n"), tt.want) {
+				t.Errorf("problems = %q, want %q", messages, tt.want)
+			}
+			if result.Listed != tt.listed || result.Authenticated != (tt.wantKinds != ProblemCredential) {
+				t.Errorf("Listed = %v, Authenticated = %v", result.Listed, result.Authenticated)
+			}
+			// A policy file that cannot be read does not prevent creating keys
+			if result.OK() != (tt.wantKinds == "" || tt.wantKinds == ProblemPolicy) {
+				t.Errorf("OK() = %v", result.OK())
+			}
+		})
+	}
+}
+
+func TestResultTags(t *testing.T) {
+	result := &Result{Problems: []Problem{
+		{Kind: ProblemTag, Tag: "tag:web"},
+		{Kind: ProblemPolicy},
+		{Kind: ProblemTag, Tag: "tag:db"},
+	}}
+
+	if got := strings.Join(result.Tags(), ","); got != "tag:db,tag:web" {
+		t.Errorf("Tags() = %s", got)
+	}
+	if !result.Has(ProblemPolicy) || result.Has(ProblemScope) {
+		t.Error("Has() does not match the problems")
+	}
+}
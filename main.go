@@ -5,11 +5,12 @@ import (
 	"os"
 
 	"github.com/ironicbadger/jankey/cmd"
+	"github.com/ironicbadger/jankey/internal/redact"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", redact.Text(err.Error()))
 		os.Exit(1)
 	}
 }